@@ -0,0 +1,262 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Command optgen generates a typed options struct from an options spec, so that
+misspelled option names are compile errors rather than runtime panics.
+
+Put a directive like this next to the string constant holding the spec:
+
+	//go:generate go run github.com/gaal/go-options/cmd/optgen -s mySpec -t catOptions
+
+This writes catoptions_gen.go, declaring a catOptions struct with one field
+per canonical option and positional argument, and two functions:
+
+	func parseCatOptions(args []string) catOptions
+	func catOptionsFrom(opt options.Options) catOptions
+
+The first parses args according to mySpec; the second converts Options you
+parsed yourself, e.g. after setting up the OptionSpec further. Options that
+take no argument become bool fields; options that take one become strings,
+options that take several become string slices, map options become
+options.Map, and counters declared with bounds or an opposite become ints.
+The spec text stays the single source of truth: rerun go generate after
+changing it.
+*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gaal/go-options/options"
+)
+
+const optgenSpec = `
+optgen - generate a typed options struct from an options spec
+Usage: optgen [OPTIONS] [file.go]
+Reads the string constant holding a spec from file.go, which defaults to
+$GOFILE when run by go generate, and writes Go code for it.
+--
+s,spec=      name of the string constant holding the spec
+t,type=      name of the struct type to generate
+f,func=      name of the parse function (default: parse<Type>)
+o,output=    file to write (default: <type>_gen.go, lowercased)
+h,help       show this help
+--
+file?        Go source file with the spec
+`
+
+func main() {
+	spec := options.NewOptions(optgenSpec)
+	opt := spec.Parse(os.Args[1:])
+	if opt.GetBool("help") {
+		spec.PrintUsageAndExit("")
+	}
+	for _, name := range []string{"spec", "type"} {
+		if !opt.Have(name) {
+			spec.PrintUsageAndExit("Missing option: --" + name)
+		}
+	}
+	filename := opt.Arg("file")
+	if filename == "" {
+		filename = os.Getenv("GOFILE")
+	}
+	if filename == "" {
+		spec.PrintUsageAndExit("Missing argument: file")
+	}
+	typeName := opt.Get("type")
+	funcName := opt.Get("func")
+	if funcName == "" {
+		funcName = "parse" + upperFirst(typeName)
+	}
+	output := opt.Get("output")
+	if output == "" {
+		output = strings.ToLower(typeName) + "_gen.go"
+	}
+
+	pkg, specText, err := readSpec(filename, opt.Get("spec"))
+	if err == nil {
+		var src []byte
+		src, err = generate(pkg, opt.Get("spec"), specText, typeName, funcName)
+		if err == nil {
+			err = os.WriteFile(output, src, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "optgen:", err)
+		os.Exit(1)
+	}
+}
+
+// readSpec finds the string constant or variable called name in a Go source
+// file and returns the file's package name and the value.
+func readSpec(filename, name string) (pkg, spec string, err error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return "", "", err
+	}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || (gen.Tok != token.CONST && gen.Tok != token.VAR) {
+			continue
+		}
+		for _, s := range gen.Specs {
+			vs := s.(*ast.ValueSpec)
+			for i, id := range vs.Names {
+				if id.Name != name {
+					continue
+				}
+				if i >= len(vs.Values) {
+					return "", "", fmt.Errorf("%s: %s has no value", fset.Position(id.Pos()), name)
+				}
+				spec, err := stringValue(vs.Values[i])
+				if err != nil {
+					return "", "", fmt.Errorf("%s: %s: %v", fset.Position(id.Pos()), name, err)
+				}
+				return f.Name.Name, spec, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("%s: no constant named %s", filename, name)
+}
+
+// stringValue evaluates a string literal, or a concatenation of them.
+func stringValue(e ast.Expr) (string, error) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			return strconv.Unquote(e.Value)
+		}
+	case *ast.ParenExpr:
+		return stringValue(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			x, err := stringValue(e.X)
+			if err != nil {
+				return "", err
+			}
+			y, err := stringValue(e.Y)
+			return x + y, err
+		}
+	}
+	return "", fmt.Errorf("not a string literal")
+}
+
+// field is a field of the generated struct.
+type field struct {
+	name, typ, expr, doc string
+}
+
+// generate returns the source of the struct type and functions for spec.
+func generate(pkg, specName, specText, typeName, funcName string) (src []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil { // NewOptions panics on bad specs.
+			err = fmt.Errorf("%s: %v", specName, r)
+		}
+	}()
+	spec := options.NewOptions(specText)
+
+	var fields []field
+	seen := make(map[string]string)
+	add := func(f field, what string) error {
+		if prev, dup := seen[f.name]; dup {
+			return fmt.Errorf("%s: %s and %s both map to field %s", specName, prev, what, f.name)
+		}
+		seen[f.name] = what
+		fields = append(fields, f)
+		return nil
+	}
+	for _, info := range spec.OptionInfo() {
+		c := info.Canonical()
+		f := field{name: goName(c), typ: "bool", expr: fmt.Sprintf("opt.GetBool(%q)", c), doc: info.Help}
+		switch {
+		case info.Counter:
+			f.typ, f.expr = "int", fmt.Sprintf("opt.GetInt(%q)", c)
+		case info.Map:
+			f.typ, f.expr = "options.Map", fmt.Sprintf("opt.GetMap(%q)", c)
+		case len(info.Metavars) > 1:
+			f.typ, f.expr = "[]string", fmt.Sprintf("opt.GetList(%q)", c)
+		case info.RequiresArg:
+			f.typ, f.expr = "string", fmt.Sprintf("opt.Get(%q)", c)
+		}
+		if err := add(f, "option "+c); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range spec.ArgInfo() {
+		f := field{name: goName(arg.Name), typ: "string", expr: fmt.Sprintf("opt.Arg(%q)", arg.Name),
+			doc: "Positional argument " + arg.Name}
+		if arg.Variadic {
+			f.typ, f.expr = "[]string", fmt.Sprintf("opt.Args(%q)", arg.Name)
+		}
+		if err := add(f, "argument "+arg.Name); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []field{
+		{"Flags", "[][]string", "opt.Flags", "Flags as presented on the command line"},
+		{"Extra", "[]string", "opt.Extra", "Non-option arguments"},
+		{"Leftover", "[]string", "opt.Leftover", `Arguments after "--"`},
+	} {
+		if err := add(f, f.name); err != nil {
+			return nil, err
+		}
+	}
+
+	fromName := typeName + "From"
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by optgen -s %s -t %s; DO NOT EDIT.\n\n", specName, typeName)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import \"github.com/gaal/go-options/options\"\n\n")
+	fmt.Fprintf(&b, "// %s holds the options and arguments declared in %s.\n", typeName, specName)
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t%s %s // %s\n", f.name, f.typ, strings.TrimSpace(f.doc))
+	}
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "// %s parses args according to %s.\n", funcName, specName)
+	fmt.Fprintf(&b, "func %s(args []string) %s {\n", funcName, typeName)
+	fmt.Fprintf(&b, "\treturn %s(options.NewOptions(%s).Parse(args))\n}\n\n", fromName, specName)
+	fmt.Fprintf(&b, "// %s converts the result of parsing a command line according to %s.\n", fromName, specName)
+	fmt.Fprintf(&b, "func %s(opt options.Options) %s {\n", fromName, typeName)
+	fmt.Fprintf(&b, "\treturn %s{\n", typeName)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", f.name, f.expr)
+	}
+	fmt.Fprintf(&b, "\t}\n}\n")
+	return format.Source(b.Bytes())
+}
+
+// goName turns an option or argument name into an exported Go identifier,
+// e.g. "input-encoding" into "InputEncoding".
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' }) {
+		b.WriteString(upperFirst(part))
+	}
+	id := b.String()
+	if id == "" || !unicode.IsLetter([]rune(id)[0]) {
+		id = "Opt" + id
+	}
+	return id
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
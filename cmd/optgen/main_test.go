@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSpec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.go")
+	src := "package foo\n\nconst (\n\tother = 1\n\tmySpec = \"synopsis\\n\" + (`--\n` + `a,bbb= doc`)\n)\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, spec, err := readSpec(path, "mySpec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != "foo" || spec != "synopsis\n--\na,bbb= doc" {
+		t.Errorf("readSpec()=%q, %q, want=%q, %q", pkg, spec, "foo", "synopsis\n--\na,bbb= doc")
+	}
+	if _, _, err := readSpec(path, "other"); err == nil {
+		t.Errorf("readSpec of a non-string: expected error")
+	}
+	if _, _, err := readSpec(path, "missing"); err == nil {
+		t.Errorf("readSpec of a missing constant: expected error")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("foo", "mySpec", "synopsis\n--\na,bbb= doc a\ndry_run doc d\npoint=X,Y doc p\nD,define=K=V doc d\nv,verbose{0,} doc v\nq,quiet~verbose doc q\n--\nfiles* doc f",
+		"fooOptions", "parseFooOptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package foo\n",
+		"\tBbb      string      // doc a\n",
+		"\tDryRun   bool        // doc d\n",
+		"\tPoint    []string    // doc p\n",
+		"\tDefine   options.Map // doc d\n",
+		"\tVerbose  int         // doc v\n",
+		"\tFiles    []string    // Positional argument files\n",
+		"func parseFooOptions(args []string) fooOptions {\n",
+		"\treturn fooOptionsFrom(options.NewOptions(mySpec).Parse(args))\n",
+		"\t\tBbb:      opt.Get(\"bbb\"),\n",
+		"\t\tDryRun:   opt.GetBool(\"dry_run\"),\n",
+		"\t\tPoint:    opt.GetList(\"point\"),\n",
+		"\t\tDefine:   opt.GetMap(\"define\"),\n",
+		"\t\tVerbose:  opt.GetInt(\"verbose\"),\n",
+		"\t\tFiles:    opt.Args(\"files\"),\n",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, src)
+		}
+	}
+
+	for _, spec := range []string{
+		"synopsis\n--\nextra doc",                // Clashes with the Extra field.
+		"synopsis\n--\ndry-run doc\ndry_run doc", // Both are DryRun.
+		"synopsis\n--\n=bad doc",
+	} {
+		if _, err := generate("foo", "mySpec", spec, "fooOptions", "parseFooOptions"); err == nil {
+			t.Errorf("generate(%q): expected error", spec)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"input-encoding": "InputEncoding",
+		"v":              "V",
+		"dry_run":        "DryRun",
+		"2fa":            "Opt2fa",
+	} {
+		if got := goName(name); got != want {
+			t.Errorf("goName(%q)=%q, want=%q", name, got, want)
+		}
+	}
+}
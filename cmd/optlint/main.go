@@ -0,0 +1,199 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Command optlint reports problems with options specs, all at once and with
+line and column numbers; see options.ValidateSpec for what it looks for.
+
+It checks files holding spec text, and Go files, in which it checks the
+specs given to options.NewOptions as string literals or as constants
+declared in the same file:
+
+	optlint cmd/mytool/main.go
+
+It exits with status 1 if it finds any problems.
+*/
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gaal/go-options/options"
+)
+
+const optlintSpec = `
+optlint - check options specs for problems
+Usage: optlint [OPTIONS] file...
+Checks files holding spec text, or the constant specs passed to
+options.NewOptions in Go files.
+--
+e,errors-only   only report problems that make NewOptions panic
+h,help          show this help
+--
+files*          spec or Go files to check
+`
+
+func main() {
+	spec := options.NewOptions(optlintSpec)
+	opt := spec.Parse(os.Args[1:])
+	if opt.GetBool("help") {
+		spec.PrintUsageAndExit("")
+	}
+	if len(opt.Args("files")) == 0 {
+		spec.PrintUsageAndExit("Missing argument: file")
+	}
+	found := false
+	for _, filename := range opt.Args("files") {
+		var (
+			probs []problem
+			err   error
+		)
+		if strings.HasSuffix(filename, ".go") {
+			probs, err = lintGoFile(filename)
+		} else {
+			probs, err = lintSpecFile(filename)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "optlint:", err)
+			os.Exit(2)
+		}
+		found = report(os.Stdout, probs, opt.GetBool("errors-only")) || found
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// problem is a problem with a spec, at a position in a file.
+type problem struct {
+	pos token.Position
+	err options.SpecError
+}
+
+// report writes problems to w and tells whether there were any.
+func report(w io.Writer, probs []problem, errorsOnly bool) bool {
+	found := false
+	for _, p := range probs {
+		if errorsOnly && !p.err.Fatal {
+			continue
+		}
+		found = true
+		kind := "error"
+		if !p.err.Fatal {
+			kind = "warning"
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", p.pos, kind, p.err.Msg)
+	}
+	return found
+}
+
+// lintSpecFile checks a file holding the text of a spec.
+func lintSpecFile(filename string) ([]problem, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var probs []problem
+	for _, e := range options.ValidateSpec(string(data)) {
+		probs = append(probs, problem{token.Position{Filename: filename, Line: e.Line, Column: e.Column}, e})
+	}
+	return probs, nil
+}
+
+// lintGoFile checks the constant specs given to NewOptions in a Go file.
+func lintGoFile(filename string) ([]problem, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]ast.Expr) // Constants and variables, by name.
+	ast.Inspect(f, func(n ast.Node) bool {
+		if vs, ok := n.(*ast.ValueSpec); ok {
+			for i, id := range vs.Names {
+				if i < len(vs.Values) {
+					values[id.Name] = vs.Values[i]
+				}
+			}
+		}
+		return true
+	})
+
+	var probs []problem
+	seen := make(map[ast.Expr]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || !isNewOptions(call.Fun) {
+			return true
+		}
+		e := call.Args[0]
+		if id, ok := e.(*ast.Ident); ok {
+			if e, ok = values[id.Name]; !ok {
+				return true
+			}
+		}
+		spec, ok := stringValue(e)
+		if !ok || seen[e] {
+			return true
+		}
+		seen[e] = true
+		start := fset.Position(e.Pos())
+		lit, raw := e.(*ast.BasicLit)
+		raw = raw && strings.HasPrefix(lit.Value, "`")
+		for _, err := range options.ValidateSpec(spec) {
+			pos := start
+			if raw {
+				// Positions in raw strings map directly onto the file.
+				if pos.Line += err.Line - 1; err.Line == 1 {
+					pos.Column += err.Column // Skip the backquote.
+				} else {
+					pos.Column = err.Column
+				}
+			} else {
+				err.Msg = fmt.Sprintf("spec line %d, column %d: %s", err.Line, err.Column, err.Msg)
+			}
+			probs = append(probs, problem{pos, err})
+		}
+		return true
+	})
+	return probs, nil
+}
+
+// isNewOptions reports whether fun looks like options.NewOptions.
+func isNewOptions(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name == "NewOptions"
+	case *ast.SelectorExpr:
+		return fun.Sel.Name == "NewOptions"
+	}
+	return false
+}
+
+// stringValue evaluates a string literal, or a concatenation of them.
+func stringValue(e ast.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			s, err := strconv.Unquote(e.Value)
+			return s, err == nil
+		}
+	case *ast.ParenExpr:
+		return stringValue(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			x, ok := stringValue(e.X)
+			y, ok2 := stringValue(e.Y)
+			return x + y, ok && ok2
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintGoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	src := "package main\n\n" +
+		"import \"github.com/gaal/go-options/options\"\n\n" +
+		"const mySpec = `synopsis\n--\na,bbb doc\nc,bbb doc\n`\n\n" +
+		"func main() {\n" +
+		"\toptions.NewOptions(mySpec)\n" +
+		"\toptions.NewOptions(mySpec)\n" +
+		"\toptions.NewOptions(\"synopsis\\n--\\nv doc\")\n" +
+		"}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	probs, err := lintGoFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if !report(&out, probs, false) {
+		t.Errorf("report found no problems")
+	}
+	want := path + ":8:3: error: duplicate name: bbb\n" +
+		path + ":14:21: warning: spec line 3, column 1: canonical name v is a single letter; consider adding a long name last\n"
+	if got := out.String(); got != want {
+		t.Errorf("report:\n%s\nwant:\n%s", got, want)
+	}
+
+	out.Reset()
+	report(&out, probs, true)
+	if want := path + ":8:3: error: duplicate name: bbb\n"; out.String() != want {
+		t.Errorf("report of errors only:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,46 @@
+// Example use of the options library, using a struct generated by optgen.
+// Try running this with various options, including invalid ones.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+//go:generate go run github.com/gaal/go-options/cmd/optgen -s mySpec -t catOptions
+
+const mySpec = `
+cat - concatenate files to standard input
+Usage: cat [OPTIONS] file...
+This version of cat supports character set conversion.
+Fancifully, you can say "-r 3" and have everything told you three times.
+--
+n,numerate,number     number input lines
+e,escape              escape nonprintable characters
+i,input-encoding=     charset input is encoded in [utf-8]
+o,output-encoding=    charset output is encoded in [utf-8]
+r,repeat=             repeat every line some number of times [1]
+v,verbose             be verbose
+--
+files*                files to concatenate
+`
+
+func main() {
+	opt := parseCatOptions(os.Args[1:])
+
+	fmt.Printf("I will concatenate the files: %q\n", opt.Files)
+	if opt.Number {
+		fmt.Println("I will number each line")
+	}
+	if opt.Escape {
+		fmt.Println("I will escape each line")
+	}
+	if opt.Repeat != "1" {
+		fmt.Printf("I will repeat each line %s times\n", opt.Repeat)
+	}
+	if opt.Verbose {
+		fmt.Println("I will be verbose")
+	}
+	fmt.Printf("Input charset: %s\n", opt.InputEncoding)
+	fmt.Printf("Output charset: %s\n", opt.OutputEncoding)
+}
@@ -0,0 +1,40 @@
+// Code generated by optgen -s mySpec -t catOptions; DO NOT EDIT.
+
+package main
+
+import "github.com/gaal/go-options/options"
+
+// catOptions holds the options and arguments declared in mySpec.
+type catOptions struct {
+	Number         bool       // number input lines
+	Escape         bool       // escape nonprintable characters
+	InputEncoding  string     // charset input is encoded in [utf-8]
+	OutputEncoding string     // charset output is encoded in [utf-8]
+	Repeat         string     // repeat every line some number of times [1]
+	Verbose        bool       // be verbose
+	Files          []string   // Positional argument files
+	Flags          [][]string // Flags as presented on the command line
+	Extra          []string   // Non-option arguments
+	Leftover       []string   // Arguments after "--"
+}
+
+// parseCatOptions parses args according to mySpec.
+func parseCatOptions(args []string) catOptions {
+	return catOptionsFrom(options.NewOptions(mySpec).Parse(args))
+}
+
+// catOptionsFrom converts the result of parsing a command line according to mySpec.
+func catOptionsFrom(opt options.Options) catOptions {
+	return catOptions{
+		Number:         opt.GetBool("number"),
+		Escape:         opt.GetBool("escape"),
+		InputEncoding:  opt.Get("input-encoding"),
+		OutputEncoding: opt.Get("output-encoding"),
+		Repeat:         opt.Get("repeat"),
+		Verbose:        opt.GetBool("verbose"),
+		Files:          opt.Args("files"),
+		Flags:          opt.Flags,
+		Extra:          opt.Extra,
+		Leftover:       opt.Leftover,
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Argv turns the options back into a command line: parsing it with the same
+// OptionSpec yields equivalent Options. Options are written with their
+// canonical names, in alphabetical order, and only if their value differs
+// from the default. Options taking several arguments are followed by them,
+// and map options are repeated for every entry. Counts are written as
+// repeated flags, or as in "--verbose=2" if the option has a default or is
+// off; toggles that are off are written as "+name". Extra and Leftover
+// follow, after "--" from the first extra argument that looks like a flag,
+// such as one a callback passed through with Skip, so that it is not parsed
+// as one; parsing the result puts such arguments in Leftover. Unknown flags,
+// which have no canonical name, are lost.
+//
+// (The name Args is taken by positional arguments.)
+func (o *Options) Argv() []string {
+	var names []string
+	for name := range o.opts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]string, 0)
+	for _, name := range names {
+		val := o.opts[name]
+		if m, ok := o.maps[name]; ok {
+			if o.sources[name].Kind == SourceDefault {
+				continue
+			}
+			for _, key := range m.keys {
+				out = append(out, prettyFlag(name)+"="+key+"="+m.vals[key])
+			}
+			continue
+		}
+		if list, ok := o.lists[name]; ok {
+			if o.spec != nil {
+				if def, ok := o.spec.defaults[name]; ok && def == val {
+					continue
+				}
+			}
+			out = append(out, prettyFlag(name))
+			out = append(out, list...)
+			continue
+		}
+		if o.spec == nil {
+			out = append(out, prettyFlag(name)+"="+val)
+			continue
+		}
+		if def, ok := o.spec.defaults[name]; ok && def == val {
+			continue
+		}
+		if o.spec.requiresArg[name] {
+			out = append(out, prettyFlag(name)+"="+val)
+			continue
+		}
+		n, ok := parseLevel(val)
+		if !ok && o.GetBool(name) {
+			n = 1
+		}
+		if n <= 0 && o.spec.toggles[name] {
+			out = append(out, "+"+name)
+			continue
+		}
+		if _, hasDef := o.spec.defaults[name]; hasDef || n <= 0 {
+			// Repeating the flag would count up from the default, and
+			// cannot say zero.
+			out = append(out, prettyFlag(name)+"="+strconv.Itoa(n))
+			continue
+		}
+		for ; n > 0; n-- {
+			out = append(out, prettyFlag(name))
+		}
+	}
+	dashes := false // Whether "--" was written.
+	for i, extra := range o.Extra {
+		if !dashes && o.looksLikeFlag(i) {
+			out = append(out, "--")
+			dashes = true
+		}
+		if !dashes && o.spec != nil && o.spec.ResponseFiles && strings.HasPrefix(extra, "@") {
+			extra = "@" + extra
+		}
+		out = append(out, extra)
+	}
+	if len(o.Leftover) > 0 {
+		if !dashes {
+			out = append(out, "--")
+		}
+		out = append(out, o.Leftover...)
+	}
+	return out
+}
+
+// looksLikeFlag reports whether Extra[i] would be parsed as a flag if
+// written out as it is, e.g. a flag that a callback passed through with
+// Skip. Once the first nonflag ends parsing, only the first one would.
+func (o *Options) looksLikeFlag(i int) bool {
+	arg := o.Extra[i]
+	if o.spec == nil {
+		_, ok := scanFlag(arg)
+		return ok || arg == "--"
+	}
+	if i > 0 && o.spec.stopsAtFirstExtra() {
+		return false
+	}
+	_, ok := o.spec.scan(arg)
+	return ok || arg == "--"
+}
+
+// String returns Argv quoted for a POSIX shell and joined with spaces.
+func (o *Options) String() string {
+	return strings.Join(smap(shellQuote, o.Argv()), " ")
+}
+
+var shellSafe = regexp.MustCompile(`^[-\w@%+=:,./]+$`)
+
+// shellQuote quotes s so that a POSIX shell reads it as a single word.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,102 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestArgv(t *testing.T) {
+	s := NewOptions("TestArgv\n--\na,bbb,ccc= doc [def]\nd,ddd= doc [def]\ne doc\nv,verbose doc\n").SetResponseFiles(true)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"extra", "-vv", "--bbb", "it's", "-d", "def", "-e", "@@at", "--", "-v"})
+	want := []string{"--ccc=it's", "-e", "--verbose", "--verbose", "extra", "@@at", "--", "-v"}
+	if diff := cmp.Diff(want, opt.Argv()); diff != "" {
+		t.Errorf("opt.Argv() diff (-want+got):\n%s", diff)
+	}
+	if got, want := opt.String(), `'--ccc=it'\''s' -e --verbose --verbose extra @@at -- -v`; got != want {
+		t.Errorf("opt.String()=%q, want=%q", got, want)
+	}
+
+	again := s.Parse(opt.Argv())
+	if diff := cmp.Diff(opt.opts, again.opts); diff != "" {
+		t.Errorf("reparsed options diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(opt.Extra, again.Extra); diff != "" {
+		t.Errorf("reparsed extra diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(opt.Leftover, again.Leftover); diff != "" {
+		t.Errorf("reparsed leftover diff (-want+got):\n%s", diff)
+	}
+
+	s = NewOptions("TestArgv\n--\npoint=X,Y doc [0 0]\nsize=W,H doc [1 1]")
+	opt = s.Parse([]string{"--point", "1", "2 3", "--size", "1", "1"})
+	if diff := cmp.Diff([]string{"--point", "1", "2 3"}, opt.Argv()); diff != "" {
+		t.Errorf("lists: Argv() diff (-want+got):\n%s", diff)
+	}
+
+	opt = s.Parse(nil)
+	if diff := cmp.Diff([]string{}, opt.Argv()); diff != "" {
+		t.Errorf("defaults only: Argv() diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestArgv_counterDefault(t *testing.T) {
+	s := NewOptions("TestArgv_counterDefault\n--\nv,verbose doc [1]\nq,quiet doc")
+	s.Exit = exitToPanic
+	for _, args := range [][]string{nil, {"-v"}, {"-vv"}, {"--verbose=0"}, {"-q", "-q"}} {
+		opt := s.Parse(args)
+		again := s.Parse(opt.Argv())
+		if diff := cmp.Diff(opt.opts, again.opts); diff != "" {
+			t.Errorf("Parse(%q): reparsed options diff (-want+got):\n%s", args, diff)
+		}
+	}
+}
+
+func TestArgv_off(t *testing.T) {
+	s := NewOptions("TestArgv_off\n--\nx,xrm+ doc\nc,color+ doc [1]\nv,verbose doc")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"--color=false", "+xrm"})
+	opt.Set("verbose", "false", Source{Kind: SourceArgs})
+	if diff := cmp.Diff([]string{"+color", "--verbose=0", "+xrm"}, opt.Argv()); diff != "" {
+		t.Errorf("opt.Argv() diff (-want+got):\n%s", diff)
+	}
+	again := s.Parse(opt.Argv())
+	for _, name := range []string{"color", "verbose", "xrm"} {
+		if !again.Have(name) || again.GetBool(name) {
+			t.Errorf("reparsed %s=%q, want it off", name, again.Get(name))
+		}
+	}
+}
+
+func TestArgv_skippedFlags(t *testing.T) {
+	s := NewOptions("TestArgv_skippedFlags\n--\nf,file= doc\nv doc")
+	s.Exit = exitToPanic
+	s.ContextCallback = func(c *ParseContext) error {
+		if c.Canonical == "file" {
+			return Skip
+		}
+		c.Store()
+		return nil
+	}
+	opt := s.Parse([]string{"a", "-f", "b", "-v", "--", "c"})
+	if diff := cmp.Diff([]string{"a", "-f", "b"}, opt.Extra); diff != "" {
+		t.Fatalf("extra diff (-want+got):\n%s", diff)
+	}
+	want := []string{"-v", "a", "--", "-f", "b", "c"}
+	if diff := cmp.Diff(want, opt.Argv()); diff != "" {
+		t.Errorf("opt.Argv() diff (-want+got):\n%s", diff)
+	}
+	s.ContextCallback = nil
+	again := s.Parse(opt.Argv())
+	if got := again.Have("file"); got {
+		t.Errorf("reparsed file=%q, want no value", again.Get("file"))
+	}
+
+	// Once the first extra argument ends parsing, the rest are safe.
+	s = NewOptions("+TestArgv_skippedFlags\n--\nf,file= doc")
+	opt = s.Parse([]string{"run", "-f", "x"})
+	if diff := cmp.Diff([]string{"run", "-f", "x"}, opt.Argv()); diff != "" {
+		t.Errorf("StopAtFirstExtra: opt.Argv() diff (-want+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import "errors"
+
+// Values a ContextCallback can return to control parsing, besides nil to go
+// on and other errors, which Parse reports as usage errors, after the
+// flag's Source, e.g. "--point (argument 3): ". They may be wrapped, as with
+// fmt.Errorf and %w.
+var (
+	// Stop ends parsing. The arguments not consumed yet go to Leftover,
+	// and positional arguments are not bound, so that e.g. a --version
+	// callback works without the arguments a program usually needs.
+	Stop = errors.New("options: stop parsing")
+
+	// Skip passes the flag through to Extra as it was presented. Any
+	// arguments consumed for it, including Value, are parsed again.
+	Skip = errors.New("options: skip flag")
+)
+
+// ParseContext describes a flag to OptionSpec.ContextCallback. It is only
+// valid during the call; Parse reuses it for the next flag.
+//
+// Value is set if the flag was given a value with "=", or if the flag names
+// an option that requires an argument, in which case Parse has consumed the
+// next argument. For options taking several arguments Values has them all,
+// and Value has them separated by spaces. For unknown flags Parse does not
+// guess: the callback may Peek at the next argument and take it with Next.
+// For -NUM, Name is the option it stands for and Value the number.
+type ParseContext struct {
+	Spec      *OptionSpec
+	Options   *Options // The Options that Parse is going to return
+	Flag      string   // The flag as presented, e.g. "--foo" or "-abc"
+	Name      string   // The flag without dashes
+	Canonical string   // Canonical name of the option, or "" if unknown
+	Dash      string   // "-", "--", or "+" for a toggle turned off
+	Index     int      // Index of the flag in the arguments given to Parse
+	Value     *string  // The argument of the flag, if any
+	Values    []string // The arguments of an option taking several
+	Attached  bool     // Whether Value was given with "=", as in --foo=bar
+
+	p   *parser
+	tok flagToken
+	pos argPos
+}
+
+// Peek returns the argument following the ones consumed so far, without
+// consuming it. It returns false if there are no more arguments.
+func (c *ParseContext) Peek() (string, bool) {
+	if c.p.i+1 >= len(c.p.args) {
+		return "", false
+	}
+	return c.p.args[c.p.i+1], true
+}
+
+// Next consumes the next argument and returns it. It returns false if there
+// are no more arguments.
+func (c *ParseContext) Next() (string, bool) {
+	arg, ok := c.Peek()
+	if ok {
+		c.p.i++
+	}
+	return arg, ok
+}
+
+// Source returns the source of the flag, for use with Options.Set.
+func (c *ParseContext) Source() Source {
+	return Source{Kind: SourceArgs, Name: c.pos.file, Line: c.pos.line, Index: c.pos.index, Flag: c.Flag}
+}
+
+// Store handles the flag the way Parse does without a callback, recording it
+// in Options with Value as its argument.
+func (c *ParseContext) Store() {
+	c.p.store(c.tok, c.pos, c.Value, c.Values)
+}
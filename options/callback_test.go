@@ -0,0 +1,175 @@
+package options
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestContextCallback(t *testing.T) {
+	s := NewOptions("TestContextCallback\n--\np,point doc\nv,verbose doc\nn,name= doc")
+	s.Exit = exitToPanic
+	type call struct {
+		Flag, Name, Canonical, Dash string
+		Index                       int
+		Value                       string
+		Attached                    bool
+	}
+	var calls []call
+	var point []string
+	s.SetContextCallback(func(c *ParseContext) error {
+		v := "<nil>"
+		if c.Value != nil {
+			v = *c.Value
+		}
+		calls = append(calls, call{c.Flag, c.Name, c.Canonical, c.Dash, c.Index, v, c.Attached})
+		switch {
+		case c.Canonical == "point":
+			x, _ := c.Next()
+			y, _ := c.Next()
+			point = []string{x, y}
+		case c.Canonical == "":
+			if next, ok := c.Peek(); ok && !strings.HasPrefix(next, "-") {
+				c.Next()
+			}
+		default:
+			c.Store()
+		}
+		return nil
+	})
+	opt := s.Parse([]string{"--point", "1", "2", "-v", "--verbose=3", "-n", "foo", "--unk", "val", "--name=bar", "extra"})
+	want := []call{
+		{"--point", "point", "point", "--", 0, "<nil>", false},
+		{"-v", "v", "verbose", "-", 3, "<nil>", false},
+		{"--verbose", "verbose", "verbose", "--", 4, "3", true},
+		{"-n", "n", "name", "-", 5, "foo", false},
+		{"--unk", "unk", "", "--", 7, "<nil>", false},
+		{"--name", "name", "name", "--", 9, "bar", true},
+	}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("calls diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, point); diff != "" {
+		t.Errorf("point diff (-want+got):\n%s", diff)
+	}
+	if got, want := opt.GetInt("verbose"), 3; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if got, want := opt.Get("name"), "bar"; got != want {
+		t.Errorf(`opt.Get("name")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"extra"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestContextCallback_fail(t *testing.T) {
+	dir := t.TempDir()
+	path := writeResponseFile(t, dir, "args", "\n--point 1\n")
+	s := NewOptions("TestContextCallback_fail\n--\npoint doc").SetResponseFiles(true)
+	var i int
+	var out bytes.Buffer
+	s.Exit = func(code int) { i = code }
+	s.ErrorWriter = &out
+	s.ContextCallback = func(c *ParseContext) error {
+		c.Next()
+		if _, ok := c.Next(); !ok {
+			return errors.New("Missing argument: " + c.Name)
+		}
+		return nil
+	}
+	s.Parse([]string{"@" + path})
+	if i == 0 {
+		t.Fatalf("expected failure with nonzero code, got=0")
+	}
+	if got, want := out.String(), "--point ("+path+":2, argument 0): Missing argument: point\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+}
+
+func TestContextCallback_failPosition(t *testing.T) {
+	s := NewOptions("TestContextCallback_failPosition\n--\np,point doc")
+	var out bytes.Buffer
+	s.Exit = func(int) {}
+	s.ErrorWriter = &out
+	s.ContextCallback = func(c *ParseContext) error {
+		return errors.New("bad point")
+	}
+	s.Parse([]string{"x", "-p"})
+	if got, want := out.String(), "-p (argument 1): bad point\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+}
+
+func TestContextCallback_stop(t *testing.T) {
+	s := NewOptions("TestContextCallback_stop\n--\nversion doc\nv,verbose doc\n--\nfile")
+	s.Exit = exitToPanic
+	var version bool
+	s.ContextCallback = func(c *ParseContext) error {
+		if c.Canonical == "version" {
+			version = true
+			return Stop
+		}
+		c.Store()
+		return nil
+	}
+	opt := s.Parse([]string{"-v", "--version", "-v", "--", "x"})
+	if !version {
+		t.Errorf("version callback not called")
+	}
+	if got, want := opt.GetInt("verbose"), 1; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if diff := cmp.Diff([]string{"-v", "--", "x"}, opt.Leftover); diff != "" {
+		t.Errorf("leftover diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestContextCallback_skip(t *testing.T) {
+	s := NewOptions("TestContextCallback_skip\n--\nname= doc").SetUnknownOptionsFatal(false)
+	s.Exit = exitToPanic
+	s.ContextCallback = func(c *ParseContext) error {
+		if c.Canonical == "" {
+			c.Next()
+			return Skip
+		}
+		c.Store()
+		return nil
+	}
+	opt := s.Parse([]string{"--unk", "--name", "n", "--unk2=x", "y"})
+	if got, want := opt.Get("name"), "n"; got != want {
+		t.Errorf(`opt.Get("name")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"--unk", "--unk2=x", "y"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestContextCallback_wrapped(t *testing.T) {
+	s := NewOptions("TestContextCallback_wrapped\n--\nversion doc\nname= doc").SetUnknownOptionsFatal(false)
+	s.Exit = exitToPanic
+	s.ContextCallback = func(c *ParseContext) error {
+		switch c.Canonical {
+		case "":
+			return fmt.Errorf("unknown flag %s: %w", c.Flag, Skip)
+		case "version":
+			return fmt.Errorf("version: %w", Stop)
+		}
+		c.Store()
+		return nil
+	}
+	opt := s.Parse([]string{"--unk", "--name=n", "--version", "x"})
+	if got, want := opt.Get("name"), "n"; got != want {
+		t.Errorf(`opt.Get("name")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"--unk"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"x"}, opt.Leftover); diff != "" {
+		t.Errorf("leftover diff (-want+got):\n%s", diff)
+	}
+}
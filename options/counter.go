@@ -0,0 +1,44 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import "strconv"
+
+// counter holds the bounds of a counter option, declared in the spec as in
+// "v,verbose{0,3}". An option counting down another one, declared as in
+// "q,quiet~verbose", makes it a counter even without bounds.
+type counter struct {
+	min, max       int
+	hasMin, hasMax bool
+}
+
+// clamp brings n within the bounds.
+func (c counter) clamp(n int) int {
+	if c.hasMin && n < c.min {
+		return c.min
+	}
+	if c.hasMax && n > c.max {
+		return c.max
+	}
+	return n
+}
+
+// String formats the bounds as they are declared, e.g. "{0,3}" or "{0,}".
+func (c counter) String() string {
+	var lo, hi string
+	if c.hasMin {
+		lo = strconv.Itoa(c.min)
+	}
+	if c.hasMax {
+		hi = strconv.Itoa(c.max)
+	}
+	return "{" + lo + "," + hi + "}"
+}
+
+// isCounter reports whether an option was declared as a counter.
+func (s *OptionSpec) isCounter(canonical string) bool {
+	_, ok := s.counters[canonical]
+	return ok
+}
@@ -0,0 +1,98 @@
+package options
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCounters(t *testing.T) {
+	s := NewOptions("TestCounters\n--\nv,verbose{0,3} be verbose [1]\nq,quiet~verbose be quiet\nd,debug doc")
+	s.Exit = exitToPanic
+	for _, tc := range []struct {
+		args          []string
+		verbose, argv string
+	}{
+		{nil, "1", ""},
+		{[]string{"-vv"}, "3", "--verbose=3"},
+		{[]string{"-vvvvv"}, "3", "--verbose=3"},
+		{[]string{"-qqq", "-v"}, "1", ""},
+		{[]string{"--quiet", "-vq"}, "0", "--verbose=0"},
+		{[]string{"--verbose=2", "-q"}, "1", ""},
+		{[]string{"--verbose=9"}, "3", "--verbose=3"},
+	} {
+		opt := s.Parse(tc.args)
+		if got := opt.Get("verbose"); got != tc.verbose {
+			t.Errorf("Parse(%q): verbose=%q, want=%q", tc.args, got, tc.verbose)
+		}
+		if got := strings.Join(opt.Argv(), " "); got != tc.argv {
+			t.Errorf("Parse(%q): Argv()=%q, want=%q", tc.args, got, tc.argv)
+		}
+	}
+
+	opt := s.Parse([]string{"--debug=4", "-d"})
+	if got, want := opt.GetInt("debug"), 5; got != want {
+		t.Errorf(`opt.GetInt("debug")=%d, want=%d`, got, want)
+	}
+	if got, want := s.Usage, "TestCounters\n\n  -v, --verbose  be verbose [1]\n  -q, --quiet  be quiet\n  -d, --debug  doc\n"; got != want {
+		t.Errorf("s.Usage=%q, want=%q", got, want)
+	}
+
+	var out bytes.Buffer
+	s.Exit = func(int) {}
+	s.ErrorWriter = &out
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--verbose=lots"}, "Bad level: verbose: lots\n"},
+		{[]string{"--quiet=2"}, "Unexpected argument: quiet: 2\n"},
+	} {
+		out.Reset()
+		s.Parse(tc.args)
+		if got := out.String(); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("Parse(%q) error output=%q, want prefix %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCounters_badCount(t *testing.T) {
+	s := NewOptions("TestCounters_badCount\n--\nf,fff doc [on]")
+	defer func() {
+		if r := recover(); r != "Bad integer value for option: fff: on" {
+			t.Errorf("Parse counting from %q: recover()=%v", "on", r)
+		}
+	}()
+	s.Parse([]string{"-f"})
+}
+
+func TestValidateSpec_counters(t *testing.T) {
+	spec := `synopsis
+--
+v,verbose{0,3} doc [x]
+a{3,1} doc
+b{1} doc
+c,ccc={0,} doc
+q,quiet~verbose doc [2]
+r~nosuch doc
+s~ccc doc
+t,ttt{0,}~verbose doc
+w{0,3} doc [7]`
+	want := []SpecError{
+		{Line: 3, Column: 20, Msg: `default value "x" for counter option verbose is not a number`, Fatal: true},
+		{Line: 4, Column: 3, Msg: "bad bounds {3,1}: minimum above maximum", Fatal: true},
+		{Line: 5, Column: 3, Msg: "bad bounds {1}: want {min,max}", Fatal: true},
+		{Line: 6, Column: 6, Msg: "counter option ccc cannot take an argument", Fatal: true},
+		{Line: 7, Column: 21, Msg: "default value for option counting down verbose is ignored"},
+		{Line: 8, Column: 3, Msg: `~ must name an option without arguments declared above: "nosuch"`, Fatal: true},
+		{Line: 9, Column: 3, Msg: `~ must name an option without arguments declared above: "ccc"`, Fatal: true},
+		{Line: 10, Column: 6, Msg: "option ttt counts down verbose and cannot have bounds of its own", Fatal: true},
+		{Line: 11, Column: 12, Msg: "default value 7 for counter option w is outside its bounds {0,3}", Fatal: true},
+		{Line: 11, Column: 1, Msg: "canonical name w is a single letter; consider adding a long name last"},
+	}
+	if diff := cmp.Diff(want, ValidateSpec(spec)); diff != "" {
+		t.Errorf("ValidateSpec diff (-want+got):\n%s", diff)
+	}
+}
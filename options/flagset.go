@@ -0,0 +1,204 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FromFlagSet builds an OptionSpec from the flags defined in fs, so that
+// programs can parse flags registered by libraries using package flag, e.g.
+// on flag.CommandLine. Boolean flags take no argument; others take one,
+// with the type from their usage text as metavariable. Flag defaults become
+// option defaults. The spec starts with synopsis, or a line naming fs if
+// synopsis is empty. Since users of package flag write "-name",
+// SingleDashLong is set.
+//
+// Flags whose names are not valid option names, such as the "test." flags,
+// are left out. After parsing, Options.Apply sets the flags in fs.
+func FromFlagSet(synopsis string, fs *flag.FlagSet) *OptionSpec {
+	if synopsis == "" {
+		synopsis = "Usage of " + fs.Name() + ":"
+	}
+	lines := []string{synopsis, "--"}
+	defaults := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if !validFlagName(f.Name) {
+			return
+		}
+		metavar, usage := flag.UnquoteUsage(f)
+		usage = strings.Join(strings.Fields(usage), " ")
+		if strings.HasSuffix(usage, "]") {
+			usage += " " // Not a default value.
+		}
+		line := f.Name
+		if isBoolFlag(f) {
+			if v, err := strconv.ParseBool(f.DefValue); err == nil && v {
+				defaults[f.Name] = "1"
+			}
+		} else {
+			line += "="
+			if metavar = strings.ToUpper(metavar); validFlagName(metavar) {
+				line += metavar
+			}
+			if f.DefValue != "" {
+				defaults[f.Name] = f.DefValue
+				if !strings.ContainsAny(usage+f.DefValue, "[]") {
+					usage += " [" + f.DefValue + "]"
+				}
+			}
+		}
+		lines = append(lines, line+"  "+usage)
+	})
+	s := NewOptions(strings.Join(lines, "\n")).SetSingleDashLong(true)
+	for name, def := range defaults {
+		s.defaults[name] = def
+	}
+	return s
+}
+
+// Apply sets the flags in fs to the values of the options of the same names
+// that were given on the command line, or with Set, by calling fs.Set. As
+// with package flag, Set is called once for every time a flag taking an
+// argument was given on the command line, in order, so that flag.Values
+// that accumulate see every value. Other options are set to their final
+// values, and boolean flags to "true" or "false". It returns the first error
+// from Set.
+func (o *Options) Apply(fs *flag.FlagSet) error {
+	applied := make(map[string]bool)
+	for _, f := range o.Flags {
+		name := strings.TrimLeft(f[0], "-")
+		if o.spec != nil {
+			name = o.spec.aliases[name]
+		}
+		fl := fs.Lookup(name)
+		if fl == nil || len(f) < 2 || isBoolFlag(fl) || o.Source(name).Kind != SourceArgs {
+			continue
+		}
+		if err := setFlag(fs, name, strings.Join(f[1:], " ")); err != nil {
+			return err
+		}
+		applied[name] = true
+	}
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || applied[f.Name] || !o.known[f.Name] {
+			return
+		}
+		if kind := o.Source(f.Name).Kind; kind == SourceNone || kind == SourceDefault {
+			return
+		}
+		val := o.Get(f.Name)
+		if isBoolFlag(f) {
+			val = strconv.FormatBool(o.GetBool(f.Name))
+		}
+		err = setFlag(fs, f.Name, val)
+	})
+	return err
+}
+
+// setFlag sets a flag in fs, describing the flag and value on error.
+func setFlag(fs *flag.FlagSet, name, val string) error {
+	if err := fs.Set(name, val); err != nil {
+		return fmt.Errorf("invalid value %q for flag -%s: %v", val, name, err)
+	}
+	return nil
+}
+
+// isBoolFlag reports whether a flag takes no argument.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// validFlagName reports whether a flag name can be an option name.
+func validFlagName(name string) bool {
+	if name == "" || name[0] == '-' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isWordByte(name[i]) && name[i] != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// FlagSet returns a flag.FlagSet on which every option in the spec is
+// registered under its canonical name and its long aliases, for code that
+// expects one, e.g. to call Lookup or VisitAll. The flags are backed by opt:
+// their values are those of the options, and setting a flag sets the option
+// as Options.Set does. Options taking no argument are boolean flags; setting
+// them to "true" or "false", or to a level, sets their count. Flag defaults
+// are the option defaults, and the FlagSet prints the spec's Usage.
+//
+// Values are checked against this spec, not the one opt was parsed with, so
+// opt may also be decoded from JSON. It must know every option in the spec;
+// FlagSet panics otherwise, or if opt is nil.
+func (s *OptionSpec) FlagSet(opt *Options) *flag.FlagSet {
+	if opt == nil {
+		panic("[Programmer error] FlagSet needs Options to keep the values in")
+	}
+	for name := range s.known {
+		if !opt.known[name] {
+			panic("[Programmer error] FlagSet given Options without option: " + name)
+		}
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), s.Usage) }
+	for _, info := range s.OptionInfo() {
+		canonical := info.Canonical()
+		for _, name := range info.Names {
+			if name != canonical && utf8.RuneCountInString(name) == 1 {
+				continue
+			}
+			fs.Var(&flagValue{s: s, opt: opt, name: canonical, flag: "-" + name}, name, info.Help)
+			fs.Lookup(name).DefValue = info.Default
+		}
+	}
+	return fs
+}
+
+// flagValue is the flag.Value of an option in a FlagSet.
+type flagValue struct {
+	s    *OptionSpec
+	opt  *Options
+	name string // Canonical
+	flag string // As registered, e.g. "-input-encoding"
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.opt == nil {
+		return "" // The zero value, for flag.PrintDefaults.
+	}
+	return v.opt.Get(v.name)
+}
+
+func (v *flagValue) Get() any {
+	return v.String()
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.s != nil && !v.s.requiresArg[v.name]
+}
+
+func (v *flagValue) Set(value string) error {
+	s := v.s
+	if !s.requiresArg[v.name] {
+		level, ok := parseLevel(value)
+		if !ok {
+			return fmt.Errorf("bad level %q", value)
+		}
+		value = strconv.Itoa(s.counters[v.name].clamp(level))
+	} else if err := s.validate(v.name, value); err != nil {
+		return err
+	}
+	v.opt.setFor(s, v.name, value, Source{Kind: SourceArgs, Flag: v.flag})
+	return nil
+}
@@ -0,0 +1,182 @@
+package options
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFromFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("prog", flag.ContinueOnError)
+	verbose := fs.Bool("verbose", false, "be verbose")
+	color := fs.Bool("color", true, "use colors")
+	enc := fs.String("input-encoding", "utf-8", "charset `encoding` of the input")
+	timeout := fs.Duration("timeout", time.Second, "how long to wait [per request]")
+	n := fs.Int("n", 3, "number of tries")
+	fs.Bool("test.v", false, "not an option name")
+
+	s := FromFlagSet("", fs)
+	s.Exit = exitToPanic
+	want := "Usage of prog:\n\n" +
+		"  --color  use colors\n" +
+		"  --input-encoding=ENCODING  charset encoding of the input [utf-8]\n" +
+		"  -n=INT  number of tries [3]\n" +
+		"  --timeout=DURATION  how long to wait [per request] \n" +
+		"  --verbose  be verbose\n"
+	if diff := cmp.Diff(want, s.Usage); diff != "" {
+		t.Errorf("s.Usage diff (-want+got):\n%s", diff)
+	}
+
+	opt := s.Parse([]string{"-verbose", "-color=false", "-timeout", "5s", "extra"})
+	if got, want := opt.Get("timeout"), "5s"; got != want {
+		t.Errorf(`opt.Get("timeout")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Get("input-encoding"), "utf-8"; got != want {
+		t.Errorf(`opt.Get("input-encoding")=%q, want=%q`, got, want)
+	}
+	if err := opt.Apply(fs); err != nil {
+		t.Fatal(err)
+	}
+	if !*verbose || *color || *timeout != 5*time.Second || *enc != "utf-8" || *n != 3 {
+		t.Errorf("flags after Apply: verbose=%t color=%t timeout=%v input-encoding=%q n=%d",
+			*verbose, *color, *timeout, *enc, *n)
+	}
+	var set []string
+	fs.Visit(func(f *flag.Flag) { set = append(set, f.Name) })
+	if diff := cmp.Diff([]string{"color", "timeout", "verbose"}, set); diff != "" {
+		t.Errorf("flags set diff (-want+got):\n%s", diff)
+	}
+
+	opt = s.Parse([]string{"-n", "many"})
+	if err := opt.Apply(fs); err == nil || !strings.Contains(err.Error(), `invalid value "many" for flag -n`) {
+		t.Errorf("Apply with a bad value: err=%v", err)
+	}
+}
+
+// tags is a flag.Value that accumulates its values.
+type tags []string
+
+func (t *tags) String() string     { return strings.Join(*t, ",") }
+func (t *tags) Set(v string) error { *t = append(*t, v); return nil }
+
+func TestFromFlagSet_accumulating(t *testing.T) {
+	fs := flag.NewFlagSet("prog", flag.ContinueOnError)
+	var tg tags
+	fs.Var(&tg, "tag", "add a `tag`")
+	verbose := fs.Bool("v", false, "be verbose")
+
+	s := FromFlagSet("", fs)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-tag", "a", "-v", "-tag=b", "--tag", "c"})
+	if err := opt.Apply(fs); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tags{"a", "b", "c"}, tg); diff != "" {
+		t.Errorf("tags diff (-want+got):\n%s", diff)
+	}
+	if !*verbose {
+		t.Errorf("verbose not set")
+	}
+}
+
+func TestFlagSet(t *testing.T) {
+	s := NewOptions("TestFlagSet\n--\nv,verbose{0,2}  be verbose\nD,define=NAME=VALUE  define a name\np,point=X,Y  a point\ni,input-encoding=  charset [utf-8]\nsecret*  hidden")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-v"})
+	fs := s.FlagSet(&opt)
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	want := []string{"define", "input-encoding", "point", "secret", "verbose"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("flag names diff (-want+got):\n%s", diff)
+	}
+	f := fs.Lookup("input-encoding")
+	if f.DefValue != "utf-8" || f.Value.String() != "utf-8" || f.Usage != "charset [utf-8]" {
+		t.Errorf("input-encoding flag: DefValue=%q Value=%q Usage=%q", f.DefValue, f.Value, f.Usage)
+	}
+	if got, want := fs.Lookup("verbose").Value.String(), "1"; got != want {
+		t.Errorf("verbose flag value=%q, want=%q", got, want)
+	}
+
+	err := fs.Parse([]string{"-verbose=5", "-define", "os=linux", "-point", "1 2", "-input-encoding=latin1", "file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := opt.GetInt("verbose"), 2; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if got, want := opt.Get("input-encoding"), "latin1"; got != want {
+		t.Errorf(`opt.Get("input-encoding")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Source("input-encoding").Flag, "-input-encoding"; got != want {
+		t.Errorf(`opt.Source("input-encoding").Flag=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, opt.GetList("point")); diff != "" {
+		t.Errorf("point diff (-want+got):\n%s", diff)
+	}
+	if got, _ := opt.GetMap("define").Get("os"); got != "linux" {
+		t.Errorf(`define["os"]=%q, want="linux"`, got)
+	}
+	if diff := cmp.Diff([]string{"file"}, fs.Args()); diff != "" {
+		t.Errorf("fs.Args() diff (-want+got):\n%s", diff)
+	}
+
+	fs.SetOutput(devNull{})
+	for _, args := range [][]string{
+		{"-verbose=lots"},
+		{"-define", "os"},
+		{"-point", "1"},
+	} {
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("fs.Parse(%q) succeeded, want an error", args)
+		}
+	}
+	if err := fs.Parse([]string{"-verbose=false"}); err != nil || opt.GetBool("verbose") {
+		t.Errorf(`fs.Parse("-verbose=false"): err=%v, verbose=%q`, err, opt.Get("verbose"))
+	}
+}
+
+func TestFlagSet_unmarshalled(t *testing.T) {
+	s := NewOptions("TestFlagSet_unmarshalled\n--\nv,verbose{0,2}  be verbose\nD,define=NAME=VALUE  define a name\np,point=X,Y  a point")
+	s.Exit = exitToPanic
+	data, err := json.Marshal(s.Parse([]string{"-Da=b"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opt Options
+	if err := json.Unmarshal(data, &opt); err != nil {
+		t.Fatal(err)
+	}
+	fs := s.FlagSet(&opt)
+	if !fs.Lookup("verbose").Value.(interface{ IsBoolFlag() bool }).IsBoolFlag() {
+		t.Errorf("verbose is not a boolean flag")
+	}
+	if err := fs.Parse([]string{"-verbose=3", "-point", "1 2", "-define", "c=d"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := opt.GetInt("verbose"), 2; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, opt.GetList("point")); diff != "" {
+		t.Errorf("point diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([][]string{{"a", "b"}, {"c", "d"}}, mapEntries(opt.GetMap("define"))); diff != "" {
+		t.Errorf("define diff (-want+got):\n%s", diff)
+	}
+	fs.SetOutput(devNull{})
+	if err := fs.Parse([]string{"-point", "1"}); err == nil {
+		t.Errorf("fs.Parse with one coordinate succeeded, want an error")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("FlagSet(nil) did not panic")
+		}
+	}()
+	s.FlagSet(nil)
+}
@@ -0,0 +1,185 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonOptions is the JSON form of Options.
+type jsonOptions struct {
+	Options  map[string]interface{} `json:"options"`
+	Args     map[string][]string    `json:"args,omitempty"`
+	Flags    [][]string             `json:"flags"`
+	Extra    []string               `json:"extra"`
+	Leftover []string               `json:"leftover"`
+}
+
+// MarshalJSON encodes the result of a parse as a JSON object. Its "options"
+// member maps every known canonical option name to its value, or to null if
+// it has none. Values of options that take no argument are numbers, values
+// of options taking several arguments are arrays of strings, values of map
+// options are objects, and others are strings. The "flags", "extra" and
+// "leftover" members hold the fields of the same names, and "args" the
+// positional arguments, if any were declared.
+func (o Options) MarshalJSON() ([]byte, error) {
+	j := jsonOptions{
+		Options:  make(map[string]interface{}),
+		Args:     o.args,
+		Flags:    o.Flags,
+		Extra:    o.Extra,
+		Leftover: o.Leftover,
+	}
+	for name := range o.known {
+		val, ok := o.opts[name]
+		list, isList := o.lists[name]
+		m, isMap := o.maps[name]
+		switch {
+		case !ok:
+			j.Options[name] = nil
+		case isMap:
+			j.Options[name] = m
+		case isList:
+			j.Options[name] = list
+		case o.spec != nil && !o.spec.requiresArg[name]:
+			if n, err := strconv.Atoi(val); err == nil {
+				j.Options[name] = n
+				break
+			}
+			fallthrough
+		default:
+			j.Options[name] = val
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes Options encoded by MarshalJSON, e.g. to replay a
+// recorded invocation in a test. The options known to the result are those
+// in the "options" member. The result is not tied to an OptionSpec: Get,
+// GetInt, GetBool, GetList, GetMap, Have, Arg and Args work as on the
+// Options that were encoded, but Source reports no source for any option,
+// and Argv writes every option with a value as "--name=value". Use
+// OptionSpec.UnmarshalOptions to decode Options tied to a spec.
+func (o *Options) UnmarshalJSON(data []byte) error {
+	var j struct {
+		jsonOptions
+		Options map[string]json.RawMessage `json:"options"`
+	}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*o = Options{
+		opts:     make(map[string]string),
+		known:    make(map[string]bool),
+		sources:  make(map[string]Source),
+		args:     j.Args,
+		Flags:    j.Flags,
+		Extra:    j.Extra,
+		Leftover: j.Leftover,
+	}
+	for name, raw := range j.Options {
+		o.known[name] = true
+		var val interface{}
+		if err := json.Unmarshal(raw, &val); err != nil {
+			return err
+		}
+		switch val := val.(type) {
+		case nil:
+		case string:
+			o.opts[name] = val
+		case float64:
+			n, err := strconv.Atoi(string(raw))
+			if err != nil {
+				return fmt.Errorf("options: bad value for option %s: %s", name, raw)
+			}
+			o.opts[name] = strconv.Itoa(n)
+		case []interface{}:
+			var list []string
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return fmt.Errorf("options: bad value for option %s: %s", name, raw)
+			}
+			o.opts[name] = strings.Join(list, " ")
+			o.setList(name, list)
+		case map[string]interface{}:
+			m := &Map{}
+			if err := json.Unmarshal(raw, m); err != nil {
+				return fmt.Errorf("options: bad value for option %s: %s", name, raw)
+			}
+			if m.Len() > 0 {
+				key := m.keys[m.Len()-1]
+				o.opts[name] = key + "=" + m.vals[key]
+			}
+			o.setMap(name, m)
+		default:
+			return fmt.Errorf("options: bad value for option %s: %s", name, raw)
+		}
+	}
+	if o.Flags == nil {
+		o.Flags = make([][]string, 0)
+	}
+	if o.Extra == nil {
+		o.Extra = make([]string, 0)
+	}
+	if o.Leftover == nil {
+		o.Leftover = make([]string, 0)
+	}
+	return nil
+}
+
+// UnmarshalOptions decodes Options encoded by MarshalJSON as UnmarshalJSON
+// does, and ties them to the spec they were parsed with, so that they work
+// exactly as the Options that were encoded. Every option in the "options"
+// member must be known to the spec. Values equal to the default, including
+// maps and lists with the default's entries, keep the default's text and
+// have SourceDefault as their source; the others have SourceArgs, with no
+// flag or index, since JSON does not record where they came from.
+func (s *OptionSpec) UnmarshalOptions(data []byte) (Options, error) {
+	var opt Options
+	if err := json.Unmarshal(data, &opt); err != nil {
+		return Options{}, err
+	}
+	for name := range opt.known {
+		if !s.known[name] {
+			return Options{}, fmt.Errorf("options: unknown option %s", name)
+		}
+	}
+	opt.known = s.known
+	opt.spec = s
+	for name := range opt.opts {
+		src := Source{Kind: SourceArgs}
+		if def, ok := s.defaults[name]; ok && opt.isDefault(s, name, def) {
+			opt.opts[name] = def
+			src = Source{Kind: SourceDefault}
+		}
+		opt.sources[name] = src
+	}
+	return opt, nil
+}
+
+// isDefault reports whether the decoded value of an option is its default.
+// Maps and lists are compared by their entries, since their JSON form does
+// not keep the default's text.
+func (o *Options) isDefault(s *OptionSpec, name, def string) bool {
+	switch {
+	case s.isMap(name):
+		m, want := o.maps[name], defaultMap(def)
+		if m == nil || m.Len() != want.Len() {
+			return false
+		}
+		for i, key := range want.keys {
+			if m.keys[i] != key || m.vals[key] != want.vals[key] {
+				return false
+			}
+		}
+		return true
+	case s.arity(name) > 1:
+		return strings.Join(o.lists[name], " ") == strings.Join(strings.Fields(def), " ")
+	}
+	return o.opts[name] == def
+}
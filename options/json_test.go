@@ -0,0 +1,123 @@
+package options
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSON(t *testing.T) {
+	s := NewOptions("TestJSON\n--\na,bbb,ccc= doc [def]\nddd= doc\nv,verbose doc\n--\nfiles* doc")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-vv", "--bbb", "12", "x", "--", "y"})
+	data, err := json.Marshal(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"options":{"ccc":"12","ddd":null,"verbose":2},"args":{"files":["x","y"]},` +
+		`"flags":[["-vv"],["--bbb","12"]],"extra":["x"],"leftover":["y"]}`
+	if got := string(data); got != want {
+		t.Errorf("json.Marshal(opt)=%s, want=%s", got, want)
+	}
+
+	var replay Options
+	if err := json.Unmarshal(data, &replay); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := replay.Get("ccc"), "12"; got != want {
+		t.Errorf(`replay.Get("ccc")=%q, want=%q`, got, want)
+	}
+	if got, want := replay.GetInt("verbose"), 2; got != want {
+		t.Errorf(`replay.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if got, want := replay.Have("ddd"), false; got != want {
+		t.Errorf(`replay.Have("ddd")=%t, want=%t`, got, want)
+	}
+	if diff := cmp.Diff(opt.Args("files"), replay.Args("files")); diff != "" {
+		t.Errorf("replay args diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(opt.Flags, replay.Flags); diff != "" {
+		t.Errorf("replay flags diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(opt.Leftover, replay.Leftover); diff != "" {
+		t.Errorf("replay leftover diff (-want+got):\n%s", diff)
+	}
+
+	s = NewOptions("TestJSON\n--\npoint=X,Y doc")
+	data, err = json.Marshal(s.Parse([]string{"--point", "1", "2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"options":{"point":["1","2"]},"flags":[["--point","1","2"]],"extra":[],"leftover":[]}`; got != want {
+		t.Errorf("json.Marshal(lists)=%s, want=%s", got, want)
+	}
+	if err := json.Unmarshal(data, &replay); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, replay.GetList("point")); diff != "" {
+		t.Errorf("replay list diff (-want+got):\n%s", diff)
+	}
+
+	if err := json.Unmarshal([]byte(`{"options":{"ccc":true}}`), &replay); err == nil {
+		t.Errorf("bad option value: expected error")
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	s := NewOptions("TestUnmarshalOptions\n--\na,bbb,ccc= doc [def]\nddd= doc [x]\nv,verbose doc")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-vv", "--bbb", "12"})
+	data, err := json.Marshal(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := s.UnmarshalOptions(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(opt.Argv(), replay.Argv()); diff != "" {
+		t.Errorf("replay Argv() diff (-want+got):\n%s", diff)
+	}
+	if got, want := replay.Source("ddd").Kind, SourceDefault; got != want {
+		t.Errorf(`replay.Source("ddd").Kind=%v, want=%v`, got, want)
+	}
+	if got, want := replay.Source("ccc").Kind, SourceArgs; got != want {
+		t.Errorf(`replay.Source("ccc").Kind=%v, want=%v`, got, want)
+	}
+
+	if _, err := s.UnmarshalOptions([]byte(`{"options":{"eee":"1"}}`)); err == nil {
+		t.Errorf("unknown option: expected error")
+	}
+	for _, n := range []string{"2.0", "1e3"} {
+		if _, err := s.UnmarshalOptions([]byte(`{"options":{"verbose":` + n + `}}`)); err == nil {
+			t.Errorf("verbose=%s: expected error", n)
+		}
+	}
+}
+
+func TestUnmarshalOptions_defaultMap(t *testing.T) {
+	s := NewOptions("TestUnmarshalOptions_defaultMap\n--\nD,define=NAME=VALUE doc [a=1 b=2]\nE,env=NAME=VALUE doc [c=3]\nv doc")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-Ee=5", "-v"})
+	data, err := json.Marshal(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := s.UnmarshalOptions(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := replay.Get("define"), "a=1 b=2"; got != want {
+		t.Errorf(`replay.Get("define")=%q, want=%q`, got, want)
+	}
+	if got, want := replay.Source("define").String(), "default"; got != want {
+		t.Errorf(`replay.Source("define")=%q, want=%q`, got, want)
+	}
+	if got, want := replay.Source("env").String(), "command line"; got != want {
+		t.Errorf(`replay.Source("env")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff(opt.Argv(), replay.Argv()); diff != "" {
+		t.Errorf("replay Argv() diff (-want+got):\n%s", diff)
+	}
+}
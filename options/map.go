@@ -0,0 +1,150 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Map holds the entries of a map option, declared in the spec with a
+// metavariable containing "=", as in "D,define=NAME=VALUE". Keys are kept
+// in the order in which they were first given. The zero Map is empty.
+type Map struct {
+	keys []string
+	vals map[string]string
+}
+
+// Len returns the number of entries.
+func (m Map) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys in order.
+func (m Map) Keys() []string {
+	return m.keys
+}
+
+// Get returns the value for key, and whether there is one.
+func (m Map) Get(key string) (string, bool) {
+	val, ok := m.vals[key]
+	return val, ok
+}
+
+// set adds an entry or replaces its value, keeping its position.
+func (m *Map) set(key, val string) {
+	if m.vals == nil {
+		m.vals = make(map[string]string)
+	}
+	if _, ok := m.vals[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.vals[key] = val
+}
+
+// MarshalJSON encodes the map as a JSON object, with keys in order.
+func (m Map) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.vals[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object of strings, keeping the order of keys.
+func (m *Map) UnmarshalJSON(data []byte) error {
+	*m = Map{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("options: map is not a JSON object: %s", data)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string) // Object keys are always strings.
+		var val string
+		if err := dec.Decode(&val); err != nil {
+			return fmt.Errorf("options: bad value for map key %s: %v", key, err)
+		}
+		m.set(key, val)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// KeyPolicy says what happens when a map option is given the same key twice.
+type KeyPolicy int
+
+const (
+	LastKeyWins        KeyPolicy = iota // The last value replaces earlier ones
+	FirstKeyWins                        // Later values are ignored
+	DuplicateKeysFatal                  // It is a usage error
+)
+
+// isMap reports whether an option is a map option.
+func (s *OptionSpec) isMap(canonical string) bool {
+	metavars := s.metavars[canonical]
+	return len(metavars) == 1 && strings.Contains(metavars[0], "=")
+}
+
+// mapEntry splits the argument of a map option at the first "=".
+func mapEntry(arg string) (key, val string, ok bool) {
+	i := strings.IndexByte(arg, '=')
+	if i < 0 {
+		return "", "", false
+	}
+	return arg[:i], arg[i+1:], true
+}
+
+// defaultMap parses the default of a map option, entries separated by spaces,
+// as in "[os=linux arch=amd64]".
+func defaultMap(def string) *Map {
+	m := &Map{}
+	for _, arg := range strings.Fields(def) {
+		if key, val, ok := mapEntry(arg); ok {
+			m.set(key, val)
+		}
+	}
+	return m
+}
+
+// scanMapFlag recognizes a short map option with its argument attached, as in
+// "-Dkey=value", which scanFlag cannot split correctly.
+func (s *OptionSpec) scanMapFlag(arg string) (tok flagToken, ok bool) {
+	if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+		return tok, false
+	}
+	if long, ok := scanFlag(arg); ok && s.SingleDashLong && len(long.name) > 1 {
+		if _, known := s.aliases[long.name]; known {
+			return tok, false // -define=x is not -d efine=x.
+		}
+	}
+	_, size := utf8.DecodeRuneInString(arg[1:])
+	end := 1 + size
+	if end == len(arg) || arg[end] == '=' || !s.isMap(s.aliases[arg[1:end]]) {
+		return tok, false
+	}
+	return flagToken{flag: arg[:end], dash: "-", name: arg[1:end], value: arg[end:], hasValue: true}, true
+}
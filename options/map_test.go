@@ -0,0 +1,107 @@
+package options
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func mapEntries(m Map) [][]string {
+	var out [][]string
+	for _, key := range m.Keys() {
+		val, _ := m.Get(key)
+		out = append(out, []string{key, val})
+	}
+	return out
+}
+
+func TestMapOptions(t *testing.T) {
+	s := NewOptions("TestMapOptions\n--\nD,define=NAME=VALUE doc [os=plan9]\nv,verbose doc")
+	s.Exit = exitToPanic
+	if got, want := s.Usage, "TestMapOptions\n\n  -D, --define=NAME=VALUE  doc [os=plan9]\n  -v, --verbose  doc\n"; got != want {
+		t.Errorf("s.Usage=%q, want=%q", got, want)
+	}
+
+	opt := s.Parse(nil)
+	if diff := cmp.Diff([][]string{{"os", "plan9"}}, mapEntries(opt.GetMap("define"))); diff != "" {
+		t.Errorf("default map diff (-want+got):\n%s", diff)
+	}
+
+	opt = s.Parse([]string{"-D", "os=linux", "-Darch=amd64", "-v", "--define=tags=a=b", "--define", "os=darwin", "-vD", "x="})
+	want := [][]string{{"os", "darwin"}, {"arch", "amd64"}, {"tags", "a=b"}, {"x", ""}}
+	if diff := cmp.Diff(want, mapEntries(opt.GetMap("define"))); diff != "" {
+		t.Errorf("map diff (-want+got):\n%s", diff)
+	}
+	if got, want := opt.Get("define"), "x="; got != want {
+		t.Errorf(`opt.Get("define")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.GetInt("verbose"), 2; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	wantArgv := []string{"--define=os=darwin", "--define=arch=amd64", "--define=tags=a=b", "--define=x=", "--verbose", "--verbose"}
+	if diff := cmp.Diff(wantArgv, opt.Argv()); diff != "" {
+		t.Errorf("opt.Argv() diff (-want+got):\n%s", diff)
+	}
+
+	data, err := json.Marshal(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `"define":{"os":"darwin","arch":"amd64","tags":"a=b","x":""}`; !strings.Contains(got, want) {
+		t.Errorf("json.Marshal(opt)=%s, want it to contain %s", got, want)
+	}
+	var replay Options
+	if err := json.Unmarshal(data, &replay); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, mapEntries(replay.GetMap("define"))); diff != "" {
+		t.Errorf("replayed map diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestMapOptions_duplicateKeys(t *testing.T) {
+	s := NewOptions("TestMapOptions_duplicateKeys\n--\nD,define=NAME=VALUE doc")
+	s.Exit = exitToPanic
+	args := []string{"-Da=1", "-Db=2", "-Da=3"}
+	for _, tc := range []struct {
+		policy KeyPolicy
+		want   [][]string
+	}{
+		{LastKeyWins, [][]string{{"a", "3"}, {"b", "2"}}},
+		{FirstKeyWins, [][]string{{"a", "1"}, {"b", "2"}}},
+	} {
+		opt := s.SetDuplicateKeys(tc.policy).Parse(args)
+		if diff := cmp.Diff(tc.want, mapEntries(opt.GetMap("define"))); diff != "" {
+			t.Errorf("policy %d: map diff (-want+got):\n%s", tc.policy, diff)
+		}
+	}
+
+	var out bytes.Buffer
+	s.SetDuplicateKeys(DuplicateKeysFatal)
+	s.Exit = func(int) {}
+	s.ErrorWriter = &out
+	s.Parse(args)
+	if got, want := out.String(), "Duplicate key: D: a\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+
+	out.Reset()
+	s.Parse([]string{"--define", "novalue"})
+	if got, want := out.String(), "Bad argument: define: novalue (want NAME=VALUE)\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+}
+
+func TestValidateSpec_mapOptions(t *testing.T) {
+	spec := "synopsis\n--\nD,define=NAME=VALUE doc [a=1 b]\nlabel=K=V,X doc"
+	want := []SpecError{
+		{Line: 3, Column: 25, Msg: `default value "a=1 b" for map option define has an entry without "=": b`},
+		{Line: 4, Column: 7, Msg: "map option label must have a single metavariable: K=V,X", Fatal: true},
+	}
+	if diff := cmp.Diff(want, ValidateSpec(spec)); diff != "" {
+		t.Errorf("ValidateSpec diff (-want+got):\n%s", diff)
+	}
+}
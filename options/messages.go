@@ -0,0 +1,154 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MessageID identifies a message written for the users of a program, such
+// as a parse error. The comments give the arguments of each message.
+type MessageID int
+
+const (
+	MsgUnknownOption            MessageID = iota // Option name
+	MsgMissingArgument                           // Option or positional argument name
+	MsgMissingArgumentFlag                       // Option name, flag found instead of its argument
+	MsgUnexpectedArgument                        // Argument
+	MsgUnexpectedOptionArgument                  // Option name, argument
+	MsgBadArgument                               // Option name, argument, metavariable
+	MsgDuplicateKey                              // Option name, key
+	MsgBadLevel                                  // Option name, level
+	MsgDeprecated                                // Flag as presented
+	MsgDeprecatedReplacement                     // Flag as presented, replacement flag
+	MsgResponseFileDepth                         // Response file name
+	MsgResponseFileRead                          // Error reading the file
+	MsgUnterminatedQuote                         // None
+	MsgMissingOption                             // Option flag
+	MsgValueCount                                // Option name, number of values wanted
+	MsgPrompt                                    // Option flag, help text
+	MsgUsageNumeric                              // None; follows the names of the -NUM option in Usage
+	MsgUsageToggle                               // "-x" or "--name", "+x" or "+name"; a toggle option in Usage
+	MsgSecretEcho                                // Option flag
+)
+
+// Catalog provides the messages written for the users of a program in their
+// language. Use SetCatalog or SetLocale to select one. Besides messages,
+// catalogs provide the parts of Usage that are not taken from the spec, such
+// as ", -NUM"; the rest of Usage is the spec's own text.
+type Catalog interface {
+	// Message returns the text of a message with its arguments filled in,
+	// or "" to use the English text.
+	Message(id MessageID, args ...interface{}) string
+}
+
+// Messages is a Catalog of fmt.Sprintf formats, by MessageID. Formats may
+// refer to arguments by index, as in "%[2]s", to change their order.
+// Missing messages are taken from English.
+type Messages map[MessageID]string
+
+// Message formats a message, or returns "" if m does not have it.
+func (m Messages) Message(id MessageID, args ...interface{}) string {
+	format, ok := m[id]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// English is the default Catalog.
+var English = Messages{
+	MsgUnknownOption:            "Unkown option: %s",
+	MsgMissingArgument:          "Missing argument: %s",
+	MsgMissingArgumentFlag:      "Missing argument: %s: found flag %s",
+	MsgUnexpectedArgument:       "Unexpected argument: %s",
+	MsgUnexpectedOptionArgument: "Unexpected argument: %s: %s",
+	MsgBadArgument:              "Bad argument: %s: %s (want %s)",
+	MsgDuplicateKey:             "Duplicate key: %s: %s",
+	MsgBadLevel:                 "Bad level: %s: %s",
+	MsgDeprecated:               "Warning: %s is deprecated",
+	MsgDeprecatedReplacement:    "Warning: %s is deprecated; use %s instead",
+	MsgResponseFileDepth:        "response files nested too deeply: %s",
+	MsgResponseFileRead:         "cannot read response file: %v",
+	MsgUnterminatedQuote:        "unterminated quote",
+	MsgMissingOption:            "Missing option: %s",
+	MsgValueCount:               "Wrong number of values: %s: want %d",
+	MsgPrompt:                   "%[2]s (%[1]s): ",
+	MsgUsageNumeric:             ", -NUM",
+	MsgUsageToggle:              "%s/%s",
+	MsgSecretEcho:               "Cannot ask for %s without showing it; give it on the command line",
+}
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]Catalog{"en": English}
+)
+
+// RegisterCatalog makes a Catalog available to SetLocale under the name of a
+// locale, such as "de" or "pt_BR". It is meant to be called from init
+// functions.
+func RegisterCatalog(locale string, c Catalog) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalogs[locale] = c
+}
+
+// LookupCatalog returns the Catalog registered for a locale, or nil if there
+// is none. Locales are given as in the environment, e.g. "pt_BR.UTF-8"; if
+// there is no catalog for the country, the one for the language is used.
+func LookupCatalog(locale string) Catalog {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ReplaceAll(locale, "-", "_")
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	if c, ok := catalogs[locale]; ok {
+		return c
+	}
+	if i := strings.IndexByte(locale, '_'); i >= 0 {
+		return catalogs[locale[:i]]
+	}
+	return nil
+}
+
+// SetLocale selects the Catalog registered for a locale, or English if there
+// is none. An empty locale is taken from the environment variables LC_ALL,
+// LC_MESSAGES and LANG, in that order.
+func (s *OptionSpec) SetLocale(locale string) *OptionSpec {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale != "" {
+			break
+		}
+		locale = os.Getenv(env)
+	}
+	c := LookupCatalog(locale)
+	if c == nil {
+		c = English
+	}
+	return s.SetCatalog(c)
+}
+
+// SetCatalog is a convenience function designed to be chained after
+// NewOptions. It also renders Usage again, which setting the Catalog field
+// directly does not.
+func (s *OptionSpec) SetCatalog(c Catalog) *OptionSpec {
+	s.Catalog = c
+	s.renderUsage()
+	return s
+}
+
+// message returns the text of a message from the spec's Catalog.
+func (s *OptionSpec) message(id MessageID, args ...interface{}) string {
+	if s.Catalog != nil {
+		if msg := s.Catalog.Message(id, args...); msg != "" {
+			return msg
+		}
+	}
+	return English.Message(id, args...)
+}
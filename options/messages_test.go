@@ -0,0 +1,56 @@
+package options
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	RegisterCatalog("xx", Messages{
+		MsgUnknownOption:       "Option inconnue : %s",
+		MsgMissingArgumentFlag: "%[2]s trouvé au lieu de l'argument de %[1]s",
+		MsgDeprecated:          "Attention : %s est obsolète",
+	})
+	s := NewOptions("TestCatalog\n--\np,point=X,Y  a point\nold  an old option").Deprecate("old", "", "")
+	s.Exit = func(int) {}
+	var out bytes.Buffer
+	s.ErrorWriter = &out
+	s.WarningWriter = &out
+	for _, tc := range []struct {
+		locale string
+		args   []string
+		want   string
+	}{
+		{"xx_YY.UTF-8", []string{"--bogus"}, "Option inconnue : bogus\n"},
+		{"xx", []string{"--point", "1", "--old"}, "--old trouvé au lieu de l'argument de point\n"},
+		{"xx", []string{"--old"}, "Attention : --old est obsolète\n"},
+		{"xx", []string{"--point"}, "Missing argument: point\n"}, // Not in the catalog.
+		{"zz_ZZ", []string{"--bogus"}, "Unkown option: bogus\n"},
+	} {
+		out.Reset()
+		s.SetLocale(tc.locale).Parse(tc.args)
+		if got := out.String(); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("locale %q: Parse(%q) wrote %q, want prefix %q", tc.locale, tc.args, got, tc.want)
+		}
+	}
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "xx_YY")
+	if got, want := s.SetLocale("").message(MsgUnknownOption, "x"), "Option inconnue : x"; got != want {
+		t.Errorf("SetLocale from LC_MESSAGES: message=%q, want=%q", got, want)
+	}
+}
+
+func TestCatalog_usage(t *testing.T) {
+	s := NewOptions("TestCatalog_usage\n--\nn,lines=# lines to show\nc,color+ use colors")
+	s.SetCatalog(Messages{
+		MsgUsageNumeric: ", -NOMBRE",
+		MsgUsageToggle:  "%s (%s pour désactiver)",
+	})
+	want := "TestCatalog_usage\n\n  -n, --lines=, -NOMBRE  lines to show\n" +
+		"  -c (+c pour désactiver), --color (+color pour désactiver)  use colors\n"
+	if got := s.Usage; got != want {
+		t.Errorf("s.Usage=%q, want=%q", got, want)
+	}
+}
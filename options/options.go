@@ -30,6 +30,10 @@ a line containing only two dashes, then a series of option specifications:
   v,verbose             be verbose
   `)
 
+NewOptions panics if the spec is malformed. ValidateSpec, and the optlint
+command built on it, list all the problems with a spec along with their
+positions, including suspicious constructs NewOptions accepts.
+
 Then parse the command line:
 
   opt := s.Parse(os.Args[1:])
@@ -45,6 +49,9 @@ name and the one your program must use when reading values.
   opt.Get("i")               // Error! No option with that canonical name.
   opt.Get("number")          // Returns "" if the user didn't specify it.
 
+Such mistakes cause a panic at runtime. When the spec is a constant, the
+optionsvet command (see package optionscheck) can find them at build time.
+
 Get returns a string. Several very simple conversions are provided but you
 are encouraged to write your own if you need more.
 
@@ -52,6 +59,16 @@ are encouraged to write your own if you need more.
   opt.GetBool("number")      // false (by default)
   opt.GetInt("repeat")       // 1 (by default)
 
+To find out where a value came from, ask for its Source: the default in the
+spec, or the flag and position on the command line. Programs that also read
+values from the environment or configuration files can record them with Set,
+so that Explain gives a complete account:
+
+  opt.Source("input-encoding")  // E.g. "--input-encoding (argument 2)"
+  opt.Set("repeat", os.Getenv("REPEAT"),
+    options.Source{Kind: options.SourceEnv, Name: "REPEAT"})
+  fmt.Print(opt.Explain())
+
 Options either take a required argument or take no argument. Non-argument
 options have useful values exposed as bool and ints.
 
@@ -60,14 +77,123 @@ options have useful values exposed as bool and ints.
   opt.GetBool("verbose")     // true
   opt.GetInt("verbose")      // 3
 
+The "=" may be followed by names for the arguments, which Usage shows, as
+in "o,output=FILE". Naming several makes the option consume that many
+arguments, none of which may look like a flag:
+
+  point=X,Y             where to start [0 0]
+
+  // cat --point 3 4
+  opt.GetList("point")       // []string{"3", "4"}
+  opt.Get("point")           // "3 4"
+
+A metavariable containing "=" makes a map option, which adds an entry every
+time it is given, also in the attached form "-Dkey=value". OptionSpec's
+DuplicateKeys says what happens when a key is given twice.
+
+  D,define=NAME=VALUE   preprocessor definitions
+
+  // cat -D os=linux -Darch=amd64
+  opt.GetMap("define").Keys()   // []string{"os", "arch"}
+  opt.GetMap("define").Get("os")  // "linux", true
+
+One option taking an argument may be followed by "#", which lets it be
+given as a dash followed by digits, like in head -5:
+
+  n,lines=N#            how many lines to show [10]
+
+  // cat -5
+  opt.Get("lines")           // "5"
+
+Options that take no argument count how many times they are given, and can
+be set to a level explicitly, as in "--verbose=2" or "--verbose=false". A
+counter option can also have bounds, which its value is kept within and its
+default must lie within, and an opposite that counts it down. An opposite
+must be declared after the option it counts down, and has no value of its
+own:
+
+  v,verbose{0,3}        be more verbose [1]
+  q,quiet~verbose       be less verbose
+
+  // cat -vvvv -q
+  opt.GetInt("verbose")      // 2
+
+Unless some option is given as -NUM or has a digit as a short name, an
+argument that looks like a negative number, such as "-5" or "-1.5", is not a
+flag: it is the value of an option before it, or an extra argument.
+
+An option taking no argument that is followed by "+" is a toggle: "+name"
+turns it off again, as in X11 programs and set +o. Usage shows both forms.
+A ParseCallback gets the option as "+name", and a ContextCallback sees "+"
+as its Dash.
+
+  x,xrm+                use X resources
+
+  // cat -x +xrm
+  opt.GetBool("xrm")         // false
+
+Programs moving from package flag, whose users write "-verbose" rather than
+"--verbose", can set SingleDashLong. A single dash followed by several
+letters is then a long option. Short options can still be clustered if
+ClusterFallback is set too, in which case an unknown "-abc" is tried as a
+cluster.
+
+An option whose names are followed by "*" is hidden: it works as usual but
+is left out of the Usage string. Hidden options are meant for debugging and
+internal use; FullUsage lists them too, for developers who want to see
+everything.
+
+  debug-dump*=          file to dump internal state into
+
 The user can say either "--foo=bar" or "--foo bar". Short options may be
 clustered; "-abc foo" means the same as "-a -b -c=foo".
 
 Parsing stops if "--" is given on the command line.
 
+Setting ResponseFiles makes Parse replace any "@path" argument with the
+arguments read from the file at path, which may in turn name other response
+files. By default, arguments in the file are separated by whitespace,
+including newlines, and quoted as in the shell: single and double quotes and
+backslashes work as they do there, and lines starting with "#" are comments.
+Setting ResponseFileLines instead takes every line of the file as one
+argument, verbatim, so that paths such as C:\src\a b.c need no quoting;
+empty lines are skipped. Say "@@foo" to pass a literal "@foo".
+Errors caused by an argument read from a response file mention its file and
+line.
+
 The "Extra" field of the returned Options contains all non-option command line
 input. In the case of a cat command, this would be the filenames to concat.
 
+Options and non-options may be mixed freely on the command line. Setting
+StopAtFirstExtra makes the first non-option end option parsing instead, as
+POSIX requires; it and everything after it go to Extra untouched. This is
+what wrappers such as "mytool run cmd --cmd-flag" need. As with getopt, this
+mode is also selected when the spec begins with a "+" (which is not part of
+the usage string), which sets StopAtFirstExtra, or when the POSIXLY_CORRECT
+environment variable is set at the time of Parse. Setting StopAtFirstExtra
+to false does not override the environment variable; programs that must mix
+options and non-options regardless, e.g. because their non-options may look
+like options, set IgnorePosixlyCorrect.
+
+The spec may end with a second line containing only two dashes, followed by
+the program's positional arguments, one per line with optional help text.
+A name on its own is required; "name?" is optional, "name*" takes zero or
+more values and "name+" one or more. Only one argument may take more than
+one value, and only required arguments may follow it.
+
+  --
+  r,recursive   copy directories recursively
+  --
+  src+          files to copy
+  dest          where to put them
+
+Parse then checks the number of values in Extra, followed by Leftover, and
+makes them available by name. After "--", even arguments starting with a
+dash are values:
+
+  opt.Args("src")            // All but the last non-option argument.
+  opt.Arg("dest")            // The last one.
+
 By default, options permits such extra values. Setting UnknownValuesFatal
 causes it to panic when it enconters them instead.
 
@@ -76,6 +202,50 @@ on the command line, including repeated ones (which are suppressed in opt --
 it only contains the last value). This allows you to do your own handling
 of repeated options easily.
 
+Options that were renamed can keep working for a while:
+
+  spec.Deprecate("charset", "input-encoding", "will be removed in 2.0")
+
+Parse then treats "--charset" as an alias of "--input-encoding", but warns
+about it on WarningWriter and records it in the "Deprecated" field of the
+returned Options. Deprecated names are not shown in the Usage string.
+
+A "!" after the names marks an option that must be given, and Parse fails
+if it has no value, from the command line or a default. Setting Interactive
+makes Parse ask for such options instead, when standard input is a terminal
+or PromptReader is set. Answers for options marked "$" as secret are read
+with PasswordReader, which can keep them from being echoed, e.g. with
+golang.org/x/term. Without a PasswordReader, Parse fails rather than let a
+secret be typed on a terminal that shows it:
+
+  u,user=!            user to log in as [root]
+  password=!$         password of the user
+
+  spec.PasswordReader = func(in io.Reader, out io.Writer) (string, error) {
+    answer, err := term.ReadPassword(int(os.Stdin.Fd()))
+    fmt.Fprintln(out)  // The newline was not echoed either.
+    return string(answer), err
+  }
+
+Messages for users, such as parse errors and deprecation warnings, are in
+English unless the spec's Catalog says otherwise. Programs can register
+catalogs for other languages and pick one from the environment:
+
+  options.RegisterCatalog("de", options.Messages{
+    options.MsgMissingArgument: "Fehlendes Argument: %s",
+  })
+  spec.SetLocale("")  // From LC_ALL, LC_MESSAGES or LANG.
+
+Catalogs also provide the few parts of the Usage string that options adds,
+such as ", -NUM"; the rest comes from the spec, so it is up to the program
+to provide one in the user's language.
+
+To re-run a program with the same options, e.g. in a child process, turn
+them back into a command line with opt.Argv, or opt.String for a version
+quoted for the shell.
+Options can also be encoded as JSON, e.g. to log invocations, and decoded
+again to replay them in tests.
+
 By default, options does not permit unknown flags. Setting
 UnknownOptionsFatal to false causes them to be recorded in "flags" instead.
 Note that since they have no canonical name, they cannot be accessed via
@@ -113,6 +283,30 @@ OptionSpec.ParseCallback:
   opt := spec.Parse(os.Args[1:])
   // Note that the opt.Get won't work when using a custom parse callback.
 
+A callback set in OptionSpec.ContextCallback instead gets a ParseContext,
+with the flag as presented, its canonical name and position, and methods to
+look at and consume the following arguments. It returns an error, which
+Parse reports as a usage error after the flag and its position, or Stop to
+end parsing, or Skip to pass the flag through to Extra:
+
+  spec.ContextCallback = func(c *options.ParseContext) error {
+    switch c.Canonical {
+    case "point":  // Takes two arguments
+      x, _ := c.Next()
+      y, ok := c.Next()
+      if !ok {
+        return errors.New("Missing argument: " + c.Name)
+      }
+      pt = image.Pt(atoi(x), atoi(y))
+    case "version":
+      showVersion = true
+      return options.Stop
+    default:
+      c.Store()  // Do what Parse does by default
+    }
+    return nil
+  }
+
 */
 package options
 
@@ -130,9 +324,16 @@ const EX_USAGE = 64 // Exit status for incorrect command lines.
 type Options struct {
 	opts     map[string]string
 	known    map[string]bool
+	args     map[string][]string // Extra, by positional argument name.
+	lists    map[string][]string // Values of options taking several arguments.
+	maps     map[string]*Map     // Values of map options.
+	sources  map[string]Source
+	spec     *OptionSpec
 	Flags    [][]string // Original flags presented on the command line
 	Extra    []string   // Non-option command line arguments left on the command line
 	Leftover []string   // Untouched arguments (after "--")
+
+	Deprecated []Deprecation // Uses of deprecated option names
 }
 
 // Get returns the value of an option, which must be known to this parse.
@@ -173,6 +374,38 @@ func (o *Options) GetBool(flag string) bool {
 	return true
 }
 
+// GetList returns the values of an option that takes arguments, which must
+// be known to this parse, or nil if it has none. It is meant for options
+// declared with several metavariables, as in "point=X,Y", which consume
+// that many arguments; Get returns their values separated by spaces.
+func (o *Options) GetList(flag string) []string {
+	val, ok := o.opts[flag]
+	if !ok && !o.known[flag] {
+		panic(fmt.Sprintf("[Programmer error] Unknown option: %s\ndump: %+v", flag, *o))
+	}
+	if list, ok := o.lists[flag]; ok {
+		return list
+	}
+	if !ok {
+		return nil
+	}
+	return []string{val}
+}
+
+// GetMap returns the entries of a map option, which must be known to this
+// parse. A map option is declared with a metavariable containing "=", as in
+// "D,define=NAME=VALUE", and adds an entry every time it is given, with the
+// argument split at the first "=". Get returns the last argument given.
+func (o *Options) GetMap(flag string) Map {
+	if !o.known[flag] {
+		panic(fmt.Sprintf("[Programmer error] Unknown option: %s\ndump: %+v", flag, *o))
+	}
+	if m := o.maps[flag]; m != nil {
+		return *m
+	}
+	return Map{}
+}
+
 // Have returns false when an option has no default value and was not given
 // on the command line, or true otherwise.
 func (o *Options) Have(flag string) bool {
@@ -183,6 +416,26 @@ func (o *Options) Have(flag string) bool {
 	return ok
 }
 
+// Arg returns the value of a positional argument, which must be declared in
+// the spec, or the empty string if an optional argument was not given. For
+// variadic arguments it returns the first value; use Args to get them all.
+func (o *Options) Arg(name string) string {
+	if vals := o.Args(name); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// Args returns the values of a positional argument, which must be declared
+// in the spec.
+func (o *Options) Args(name string) []string {
+	vals, ok := o.args[name]
+	if !ok {
+		panic(fmt.Sprintf("[Programmer error] Unknown argument: %s\ndump: %+v", name, *o))
+	}
+	return vals
+}
+
 // GetAll is a convenience function which scans the "flags" return value of
 // OptionSpec.Parse, and gathers all the values of a given option. This must
 // be a required-argument option.
@@ -205,17 +458,45 @@ func GetAll(flag string, flags [][]string) []string {
 
 // OptionSpec represents the specification of a command line interface.
 type OptionSpec struct {
-	Usage               string // Formatted usage string
-	UnknownOptionsFatal bool   // Whether to die on unknown flags [true]
-	UnknownValuesFatal  bool   // Whether to die on extra nonflags [false]
-
-	ParseCallback func(*OptionSpec, string, *string) // Custom callback function
-	Exit          func(code int)                     // Function to use for exiting [os.Exit]
-	ErrorWriter   io.Writer                          // Alternate Writer for usage writing
+	Usage                string    // Formatted usage string
+	FullUsage            string    // Usage string including hidden options
+	UnknownOptionsFatal  bool      // Whether to die on unknown flags [true]
+	UnknownValuesFatal   bool      // Whether to die on extra nonflags [false]
+	DuplicateKeys        KeyPolicy // How map options treat repeated keys [LastKeyWins]
+	StopAtFirstExtra     bool      // Whether the first nonflag ends parsing [false]
+	SingleDashLong       bool      // Whether -name is a long option, as with package flag [false]
+	ClusterFallback      bool      // Whether unknown -abc is a cluster with SingleDashLong [false]
+	ResponseFiles        bool      // Whether to expand "@file" arguments [false]
+	MaxResponseDepth     int       // How deeply response files may nest [10]
+	ResponseFileLines    bool      // Whether response files have one verbatim argument per line [false]
+	IgnorePosixlyCorrect bool      // Whether POSIXLY_CORRECT leaves StopAtFirstExtra alone [false]
+
+	ParseCallback   func(*OptionSpec, string, *string)         // Custom callback function
+	ContextCallback func(*ParseContext) error                  // Custom callback function, preferred
+	Exit            func(code int)                             // Function to use for exiting [os.Exit]
+	ErrorWriter     io.Writer                                  // Alternate Writer for usage writing
+	WarningWriter   io.Writer                                  // Writer for warnings [os.Stderr]
+	Catalog         Catalog                                    // Messages for users; see SetCatalog [English]
+	Interactive     bool                                       // Whether to prompt for missing required options [false]
+	PromptReader    io.Reader                                  // Reader for answers to prompts [os.Stdin]
+	PromptWriter    io.Writer                                  // Writer for prompts [os.Stderr]
+	PasswordReader  func(io.Reader, io.Writer) (string, error) // Reads answers for secret options [none]
 
 	aliases     map[string]string
+	known       map[string]bool // Canonical names, shared with Options
+	negatives   bool            // Whether "-5" can only be a number
 	defaults    map[string]string
 	requiresArg map[string]bool
+	metavars    map[string][]string // Names of the arguments, by canonical name
+	counters    map[string]counter  // Bounds of counter options, by canonical name
+	decrements  map[string]bool     // Names that count an option down
+	numeric     string              // Canonical name of the option given as -NUM
+	toggles     map[string]bool     // Options that +name turns off, by canonical name
+	required    map[string]bool     // Options that must be given, by canonical name
+	secrets     map[string]bool     // Options whose prompts hide the input, by canonical name
+	positionals []positional
+	usage       []usageLine
+	deprecated  map[string]Deprecation
 }
 
 // SetUnknownOptionsFatal is a conveience function designed to be chained
@@ -232,6 +513,69 @@ func (s *OptionSpec) SetUnknownValuesFatal(val bool) *OptionSpec {
 	return s
 }
 
+// SetStopAtFirstExtra is a convenience function designed to be chained
+// after NewOptions.
+func (s *OptionSpec) SetStopAtFirstExtra(val bool) *OptionSpec {
+	s.StopAtFirstExtra = val
+	return s
+}
+
+// SetSingleDashLong is a convenience function designed to be chained after
+// NewOptions.
+func (s *OptionSpec) SetSingleDashLong(val bool) *OptionSpec {
+	s.SingleDashLong = val
+	return s
+}
+
+// SetClusterFallback is a convenience function designed to be chained after
+// NewOptions.
+func (s *OptionSpec) SetClusterFallback(val bool) *OptionSpec {
+	s.ClusterFallback = val
+	return s
+}
+
+// SetDuplicateKeys is a convenience function designed to be chained after
+// NewOptions.
+func (s *OptionSpec) SetDuplicateKeys(policy KeyPolicy) *OptionSpec {
+	s.DuplicateKeys = policy
+	return s
+}
+
+// SetIgnorePosixlyCorrect is a convenience function designed to be chained
+// after NewOptions.
+func (s *OptionSpec) SetIgnorePosixlyCorrect(val bool) *OptionSpec {
+	s.IgnorePosixlyCorrect = val
+	return s
+}
+
+// stopsAtFirstExtra reports whether the first nonflag ends parsing, because
+// of StopAtFirstExtra or POSIXLY_CORRECT.
+func (s *OptionSpec) stopsAtFirstExtra() bool {
+	_, posix := os.LookupEnv("POSIXLY_CORRECT")
+	return s.StopAtFirstExtra || posix && !s.IgnorePosixlyCorrect
+}
+
+// SetResponseFiles is a convenience function designed to be chained after
+// NewOptions.
+func (s *OptionSpec) SetResponseFiles(val bool) *OptionSpec {
+	s.ResponseFiles = val
+	return s
+}
+
+// SetResponseFileLines is a convenience function designed to be chained
+// after NewOptions.
+func (s *OptionSpec) SetResponseFileLines(val bool) *OptionSpec {
+	s.ResponseFileLines = val
+	return s
+}
+
+// SetInteractive is a convenience function designed to be chained after
+// NewOptions.
+func (s *OptionSpec) SetInteractive(val bool) *OptionSpec {
+	s.Interactive = val
+	return s
+}
+
 // SetParseCallback is a convencience function designed to be chained after
 // NewOptions.
 func (s *OptionSpec) SetParseCallback(callback func(*OptionSpec, string, *string)) *OptionSpec {
@@ -239,70 +583,211 @@ func (s *OptionSpec) SetParseCallback(callback func(*OptionSpec, string, *string
 	return s
 }
 
+// SetContextCallback is a convenience function designed to be chained after
+// NewOptions.
+func (s *OptionSpec) SetContextCallback(callback func(*ParseContext) error) *OptionSpec {
+	s.ContextCallback = callback
+	return s
+}
+
 // NewOptions takes a string speficiation of a command line interface and
-// returns an OptionSpec for you to call Parse on.
+// returns an OptionSpec for you to call Parse on. It panics if the spec has
+// problems; use ValidateSpec to get a list of them instead.
 func NewOptions(spec string) *OptionSpec {
-	// TODO(gaal): move to constant
-	flagSpec := regexp.MustCompile(`^([-\w,]+)(=?)\s+(.*)$`)
-	// Not folded into previous pattern because that would necessitate FindStringSubmatchIndex.
-	defaultValue := regexp.MustCompile(`\[(.*)\]$`)
-
-	s := &OptionSpec{UnknownOptionsFatal: true, UnknownValuesFatal: false, Exit: os.Exit}
-	s.aliases = make(map[string]string)
-	s.defaults = make(map[string]string)
-	s.requiresArg = make(map[string]bool)
-	stanza := 0 // synopsis
-	specLines := strings.Split(spec, "\n")
-	for n, l := range specLines {
-		switch stanza {
-		case 0:
-			{
-				if l == "--" {
-					s.Usage += "\n"
-					stanza++
-					continue
-				}
-				s.Usage += l + "\n"
+	s, errs := parseSpec(spec)
+	for _, err := range errs {
+		if err.Fatal {
+			panic(err.Error())
+		}
+	}
+	return s
+}
+
+// usageLine is a line of the usage string. Lines describing an option are
+// rendered from its names and help text; others are kept verbatim.
+type usageLine struct {
+	text   string   // The verbatim line, or the help text of an option.
+	names  []string // Names of the option described, if any.
+	hidden bool
+}
+
+// renderUsage sets Usage and FullUsage from the lines collected from the
+// spec.
+func (s *OptionSpec) renderUsage() {
+	s.Usage, s.FullUsage = "", ""
+	for _, l := range s.usage {
+		if l.names == nil {
+			s.Usage += l.text
+			s.FullUsage += l.text
+			continue
+		}
+		eq := ""
+		if canonical := s.aliases[l.names[len(l.names)-1]]; s.requiresArg[canonical] {
+			switch metavars := s.metavars[canonical]; len(metavars) {
+			case 0:
+				eq = "="
+			case 1:
+				eq = "=" + metavars[0]
+			default:
+				eq = " " + strings.Join(metavars, " ")
 			}
-		case 1:
-			{
-				if l == "" {
-					s.Usage += "\n"
-					continue
-				}
-				parts := flagSpec.FindStringSubmatch(l)
-				if parts == nil {
-					panic(fmt.Sprint(n, ": no parse: ", l))
-				}
-				names := strings.Split(parts[1], ",")
-				canonical := names[len(names)-1]
-				for _, name := range names {
-					if _, dup := s.aliases[name]; dup {
-						panic(fmt.Sprint(n, ": duplicate name: ", name))
-					}
-					if name == "" || name == "-" || name == "--" {
-						panic(fmt.Sprint(n, ": bad name: ", name))
-					}
-
-					s.aliases[name] = canonical
-				}
-				if parts[2] == "=" {
-					s.requiresArg[canonical] = true
+			if canonical == s.numeric && canonical == l.names[len(l.names)-1] {
+				eq += s.message(MsgUsageNumeric)
+			}
+		}
+		pretty := prettyFlag
+		if s.toggles[s.aliases[l.names[len(l.names)-1]]] {
+			pretty = func(flg string) string {
+				return s.message(MsgUsageToggle, prettyFlag(flg), "+"+flg)
+			}
+		}
+		// TODO(gaal): linewrap.
+		s.FullUsage += "  " + strings.Join(smap(pretty, l.names), ", ") + eq + "  " + l.text + "\n"
+		var names []string
+		for _, name := range l.names {
+			if _, dep := s.deprecated[name]; !dep {
+				names = append(names, name)
+			}
+		}
+		if !l.hidden && len(names) > 0 {
+			s.Usage += "  " + strings.Join(smap(pretty, names), ", ") + eq + "  " + l.text + "\n"
+		}
+	}
+}
+
+// Deprecate marks an option name as deprecated. Presenting it on the command
+// line still works, but Parse writes a warning to WarningWriter and records
+// it in Options.Deprecated. The name is left out of the Usage string.
+//
+// If replacement is not empty, it must be the canonical name of an option,
+// and name becomes an alias of it: name may be unknown to the spec, an alias
+// of replacement, or the canonical name of an option taking arguments the
+// same way as replacement, whose aliases are then all deprecated too. If
+// replacement is empty, name must be known to the spec and keeps its
+// meaning. The message, if not empty, is appended to the warning.
+func (s *OptionSpec) Deprecate(name, replacement, message string) *OptionSpec {
+	names := []string{name}
+	if replacement != "" {
+		if s.aliases[replacement] != replacement {
+			panic("[Programmer error] Unknown replacement option: " + replacement)
+		}
+		switch canonical, known := s.aliases[name]; {
+		case !known:
+			s.aliases[name] = replacement
+		case canonical == replacement:
+		case canonical == name && s.requiresArg[name] == s.requiresArg[replacement] && s.arity(name) == s.arity(replacement):
+			for alias, c := range s.aliases {
+				if c == name && alias != name {
+					s.aliases[alias] = replacement
+					names = append(names, alias)
 				}
-				if def := defaultValue.FindStringSubmatch(parts[3]); def != nil {
-					s.defaults[canonical] = def[1]
+			}
+			s.aliases[name] = replacement
+			delete(s.defaults, name)
+			for i, l := range s.usage {
+				if l.names != nil && l.names[len(l.names)-1] == name {
+					s.usage[i].hidden = true
 				}
-				// TODO(gaal): linewrap.
-				s.Usage += "  " + strings.Join(smap(prettyFlag, names), ", ") +
-					parts[2] + "  " + parts[3] + "\n"
 			}
 		default:
-			panic(fmt.Sprint(n, ": no parse: ", spec))
+			panic("[Programmer error] Cannot deprecate " + name + " in favor of " + replacement)
 		}
+	} else if _, known := s.aliases[name]; !known {
+		panic("[Programmer error] Unknown option: " + name)
+	}
+	if s.deprecated == nil {
+		s.deprecated = make(map[string]Deprecation)
 	}
+	for _, n := range names {
+		s.deprecated[n] = Deprecation{Replacement: replacement, Message: message}
+	}
+	s.indexNames()
+	s.renderUsage()
 	return s
 }
 
+// Deprecation describes the use of a deprecated option name.
+type Deprecation struct {
+	Flag        string // The flag as presented on the command line
+	Replacement string // Canonical name of the option to use instead, if any
+	Message     string // Additional explanation, if any
+}
+
+// warnDeprecated records the use of a deprecated option name, if it is one.
+func (s *OptionSpec) warnDeprecated(opt *Options, name, dash string) {
+	dep, ok := s.deprecated[name]
+	if !ok {
+		return
+	}
+	presented := dash + name
+	dep.Flag = presented
+	opt.Deprecated = append(opt.Deprecated, dep)
+	msg := s.message(MsgDeprecated, presented)
+	if dep.Replacement != "" {
+		msg = s.message(MsgDeprecatedReplacement, presented, prettyFlag(dep.Replacement))
+	}
+	if dep.Message != "" {
+		msg += ": " + dep.Message
+	}
+	w := s.WarningWriter
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintln(w, msg)
+}
+
+// indexNames records the set of canonical option names, which every
+// Options returned by Parse shares. The map is replaced, not modified, so
+// that earlier results are not affected by later changes to the spec.
+func (s *OptionSpec) indexNames() {
+	s.known = make(map[string]bool, len(s.aliases))
+	s.negatives = s.numeric == ""
+	for name, canonical := range s.aliases {
+		s.known[canonical] = true
+		if len(name) == 1 && '0' <= name[0] && name[0] <= '9' {
+			s.negatives = false
+		}
+	}
+}
+
+// positionalSpec matches a line in the positional arguments section of a
+// spec: a name, an optional kind marker and optional help text.
+var positionalSpec = regexp.MustCompile(`^(\w[-\w]*)([?*+]?)(?:\s+(.*))?$`)
+
+// positional describes a named positional argument.
+type positional struct {
+	name string
+	kind string // "" (required), "?" (optional), "*" (zero or more), "+" (one or more)
+}
+
+func (p positional) variadic() bool {
+	return p.kind == "*" || p.kind == "+"
+}
+
+// String renders the argument the way usage synopses conventionally do.
+func (p positional) String() string {
+	switch p.kind {
+	case "?":
+		return "[" + p.name + "]"
+	case "*":
+		return "[" + p.name + "...]"
+	case "+":
+		return p.name + "..."
+	}
+	return p.name
+}
+
+// ArgSynopsis returns the positional arguments declared in the spec as they
+// would appear in a synopsis, e.g. "src [dest] [files...]". It is empty when
+// the spec declares none.
+func (s *OptionSpec) ArgSynopsis() string {
+	var out []string
+	for _, p := range s.positionals {
+		out = append(out, p.String())
+	}
+	return strings.Join(out, " ")
+}
+
 // GetCanonical returns the canonical name of an option, or the empty string if
 // the option is unkown. Useful to tidy up switch statements when using the
 // custom callback interface.
@@ -310,6 +795,87 @@ func (s *OptionSpec) GetCanonical(option string) string {
 	return s.aliases[option]
 }
 
+// OptionInfo describes an option declared in a spec.
+type OptionInfo struct {
+	Names       []string // Names given in the spec; the last one is canonical
+	RequiresArg bool     // Whether the option takes an argument
+	Metavars    []string // Names of its arguments, if given in the spec
+	Map         bool     // Whether it is a map option
+	Counter     bool     // Whether it is a counter with bounds or an opposite
+	Numeric     bool     // Whether it can be given as -NUM
+	Toggle      bool     // Whether +name turns it off
+	Required    bool     // Whether it must be given
+	Secret      bool     // Whether prompts for it hide the input
+	Default     string   // Default value, if HasDefault
+	HasDefault  bool
+	Hidden      bool   // Whether the option is left out of Usage
+	Help        string // Help text, including the default
+}
+
+// Canonical returns the canonical name of the option.
+func (info OptionInfo) Canonical() string {
+	return info.Names[len(info.Names)-1]
+}
+
+// OptionInfo describes the options declared in the spec, in order. Options
+// deprecated in favor of another one are not included.
+func (s *OptionSpec) OptionInfo() []OptionInfo {
+	var out []OptionInfo
+	for _, l := range s.usage {
+		if l.names == nil {
+			continue
+		}
+		canonical := l.names[len(l.names)-1]
+		if s.aliases[canonical] != canonical {
+			continue
+		}
+		def, hasDef := s.defaults[canonical]
+		out = append(out, OptionInfo{
+			Names:       l.names,
+			RequiresArg: s.requiresArg[canonical],
+			Metavars:    s.metavars[canonical],
+			Map:         s.isMap(canonical),
+			Counter:     s.isCounter(canonical),
+			Numeric:     s.numeric == canonical,
+			Toggle:      s.toggles[canonical],
+			Required:    s.required[canonical],
+			Secret:      s.secrets[canonical],
+			Default:     def,
+			HasDefault:  hasDef,
+			Hidden:      l.hidden,
+			Help:        l.text,
+		})
+	}
+	return out
+}
+
+// arity returns the number of arguments an option consumes.
+func (s *OptionSpec) arity(canonical string) int {
+	if !s.requiresArg[canonical] {
+		return 0
+	}
+	if n := len(s.metavars[canonical]); n > 1 {
+		return n
+	}
+	return 1
+}
+
+// ArgInfo describes a positional argument declared in a spec.
+type ArgInfo struct {
+	Name     string
+	Optional bool // Whether the argument may be left out
+	Variadic bool // Whether the argument takes any number of values
+}
+
+// ArgInfo describes the positional arguments declared in the spec, in order.
+func (s *OptionSpec) ArgInfo() []ArgInfo {
+	var out []ArgInfo
+	for _, p := range s.positionals {
+		out = append(out, ArgInfo{Name: p.name, Optional: p.kind == "?" || p.kind == "*", Variadic: p.variadic()})
+	}
+	return out
+}
+
 // BUG(gaal): Negated options ("--no-frobulate") are not yet supported.
 
 // Parse performs the actual parsing of a command line according to an
@@ -319,138 +885,83 @@ func (s *OptionSpec) GetCanonical(option string) string {
 // In case of parse error, a panic is thrown.
 // TODO(gaal): decide if gentler error signalling is more useful.
 func (s *OptionSpec) Parse(args []string) Options {
-	// TODO(gaal): extract to constant.
-	flagRe := regexp.MustCompile(`^((--?)([-\w]+))(=(.*))?$`)
-
 	opt := Options{
-		opts:     make(map[string]string),
-		Flags:    make([][]string, 0),
+		opts:     make(map[string]string, len(s.defaults)),
+		known:    s.known,
+		sources:  make(map[string]Source, len(s.defaults)),
+		spec:     s,
+		Flags:    make([][]string, 0, len(args)),
 		Extra:    make([]string, 0),
 		Leftover: make([]string, 0),
 	}
-	opt.opts = make(map[string]string)
 	for flag, def := range s.defaults {
 		opt.opts[flag] = def
-	}
-	opt.known = make(map[string]bool)
-	for _, canonical := range s.aliases {
-		opt.known[canonical] = true
+		opt.sources[flag] = Source{Kind: SourceDefault}
+		if s.isMap(flag) {
+			opt.setMap(flag, defaultMap(def))
+		} else if s.arity(flag) > 1 {
+			opt.setList(flag, strings.Fields(def))
+		}
 	}
 
-	for i := 0; i < len(args); i++ { // Can't use range because we may bump i.
-		val := args[i]
-		if val == "--" {
-			opt.Leftover = append(opt.Leftover, args[i+1:]...)
-			break
-		}
-
-		flagParts := flagRe.FindStringSubmatch(val)
-		if flagParts == nil { // This is not a flag.
-			if s.UnknownValuesFatal {
-				panic("Unexpected argument: " + val + "\n" + s.Usage)
-			}
-			opt.Extra = append(opt.Extra, val)
-			continue
-		}
-		presentedFlag := flagParts[1] // "presented" by the user.
-		presentedDash := flagParts[2]
-		presentedFlagName := flagParts[3]
-		haveSelfValue := flagParts[4] != ""
-		selfValue := flagParts[5]
-		canonical, known := s.aliases[presentedFlagName]
-
-		callback := s.ParseCallback
-		if callback == nil {
-			// The standard parse parse callback is in cahoots with the parser;
-			// it closes on more state than user callbacks have access to. We
-			// need to consider exposing more of it, e.g. both the presented
-			// flag	(which is passed as a parameter now) and the canonical one
-			// are interesting. But we don't want to complicate things too much,
-			// so we'll probably not allow winding back an argument.
-			callback = func(optionSpec *OptionSpec, option string, value *string) {
-				unknown := func(k bool) bool {
-					if !k && s.UnknownOptionsFatal {
-						s.PrintUsageAndExit("Unkown option: " + option)
-						return true // not reached
-					}
-					return !k
-				}
-				if presentedDash == "-" && len(presentedFlagName) > 1 { // Clustering, -abc
-					for j, shortR := range presentedFlagName {
-						short := string(shortR)
-						isLast := j == len(presentedFlagName)-1
-						canonicalC, knownC := s.aliases[short]
-						if !unknown(knownC) {
-							if s.requiresArg[canonicalC] {
-								if value == nil || !isLast {
-									s.PrintUsageAndExit("Missing argument: " + short)
-									return // not reached
-								}
-								opt.opts[canonicalC] = *value
-							} else {
-								if value != nil && isLast {
-									s.PrintUsageAndExit("Unexpected argument: " + short + ": " + *value)
-									return // not reached
-								}
-								opt.opts[canonicalC] = fmt.Sprintf("%d", opt.GetInt(canonicalC)+1)
-							}
-						}
-					}
-				} else if !unknown(known) {
-					if s.requiresArg[canonical] {
-						if value == nil {
-							s.PrintUsageAndExit("Missing argument: " + option)
-							return // not reached
-						}
-						opt.opts[canonical] = *value
-					} else {
-						if value != nil {
-							// Unlike the above nil check, reaching here is a programming bug.
-							panic("Unexpected argument: " + option + ": " + *value)
-						}
-						opt.opts[canonical] = fmt.Sprintf("%d", opt.GetInt(canonical)+1)
-					}
-				}
-				if value != nil {
-					opt.Flags = append(opt.Flags, []string{presentedFlag, *value})
-				} else {
-					opt.Flags = append(opt.Flags, []string{presentedFlag})
-				}
-			}
+	p := parser{s: s, opt: &opt, args: args, stopAtFirstExtra: s.stopsAtFirstExtra()}
+	if s.ResponseFiles {
+		var err error
+		if p.args, p.where, err = s.expandResponseFiles(args); err != nil {
+			s.PrintUsageAndExit(err.Error())
+			return opt // not reached
 		}
+	}
+	if !p.run() {
+		return opt
+	}
+	if len(s.positionals) > 0 {
+		s.bindPositionals(&opt)
+	}
+	if len(s.required) > 0 && s.ParseCallback == nil {
+		s.requireOptions(&opt)
+	}
+	return opt
+}
 
-		maybeClustering := !known && presentedDash == "-"
-		var nextArg *string = nil
-		if i < len(args)-1 {
-			nextArg = &(args[i+1])
-		}
-		needsArg := known && s.requiresArg[canonical]
-		if !known && nextArg != nil && !strings.HasPrefix(*nextArg, "-") { // best effort unknown
-			needsArg = true
-		}
-		if maybeClustering && haveSelfValue {
-			needsArg = true
+// bindPositionals assigns opt.Extra, followed by opt.Leftover, to the
+// positional arguments declared in the spec, failing if there are too few or
+// too many of them.
+func (s *OptionSpec) bindPositionals(opt *Options) {
+	opt.args = make(map[string][]string)
+	required := 0
+	for _, p := range s.positionals {
+		if p.kind == "" || p.kind == "+" {
+			required++
 		}
-		lastClustered := string(s.aliases[presentedFlagName[len(presentedFlagName)-1:]])
-		if maybeClustering && s.requiresArg[lastClustered] {
-			needsArg = true
-		}
-		arg := func() *string {
-			if haveSelfValue {
-				return &selfValue
+	}
+	rest := append(append([]string(nil), opt.Extra...), opt.Leftover...)
+	for _, p := range s.positionals {
+		n := 0
+		switch p.kind {
+		case "":
+			n = 1
+			required--
+		case "?":
+			if len(rest) > required {
+				n = 1
 			}
-			i++
-			return nextArg
+		case "+":
+			required--
+			n = len(rest) - required
+		case "*":
+			n = len(rest) - required
 		}
-		if needsArg {
-			callback(s, presentedFlagName, arg())
-		} else {
-			callback(s, presentedFlagName, nil)
+		if n > len(rest) || n < 0 || (p.kind == "+" && n == 0) {
+			s.PrintUsageAndExit(s.message(MsgMissingArgument, p.name))
+			n = 0 // not reached
 		}
-
+		opt.args[p.name] = rest[:n]
+		rest = rest[n:]
+	}
+	if len(rest) > 0 {
+		s.PrintUsageAndExit(s.message(MsgUnexpectedArgument, rest[0]))
 	}
-
-	return opt
 }
 
 // PrintUsageAndExit writes the usage string and exits the program.
@@ -1,7 +1,9 @@
 package options
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -142,6 +144,39 @@ func TestParse_leftover(t *testing.T) {
 	}
 }
 
+func TestParse_stopAtFirstExtra(t *testing.T) {
+	args := []string{"--ccc", "myval", "run", "cmd", "--ccc", "other", "--", "x"}
+	test := func(name string, s *OptionSpec) {
+		s.Exit = exitToPanic
+		opt := s.Parse(args)
+		if got, want := opt.Get("ccc"), "myval"; got != want {
+			t.Errorf(`%s: opt.Get("ccc")=%q, want=%q`, name, got, want)
+		}
+		if diff := cmp.Diff([]string{"run", "cmd", "--ccc", "other", "--", "x"}, opt.Extra); diff != "" {
+			t.Errorf("%s: extra diff (-want+got):\n%s", name, diff)
+		}
+		if len(opt.Leftover) > 0 {
+			t.Errorf("%s: leftover args: %q", name, opt.Leftover)
+		}
+	}
+	test("field", NewOptions("TestParse_stopAtFirstExtra\n--\nccc= doc").SetStopAtFirstExtra(true))
+	test("leading plus", NewOptions("+TestParse_stopAtFirstExtra\n--\nccc= doc"))
+	s := NewOptions("TestParse_stopAtFirstExtra\n--\nccc= doc")
+	t.Setenv("POSIXLY_CORRECT", "1") // After NewOptions, but before Parse.
+	test("POSIXLY_CORRECT", s)
+	if s.StopAtFirstExtra {
+		t.Errorf("POSIXLY_CORRECT changed s.StopAtFirstExtra")
+	}
+
+	opt := s.SetIgnorePosixlyCorrect(true).Parse(args)
+	if got, want := opt.Get("ccc"), "other"; got != want {
+		t.Errorf(`IgnorePosixlyCorrect: opt.Get("ccc")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"run", "cmd"}, opt.Extra); diff != "" {
+		t.Errorf("IgnorePosixlyCorrect: extra diff (-want+got):\n%s", diff)
+	}
+}
+
 func TestParse_unknownFlags(t *testing.T) {
 	s := NewOptions("TestParse_unknownFlags\n--\nccc= doc [def]")
 	var i int
@@ -205,6 +240,297 @@ d,bbb,eee an option with dupe`
 	// tu.ExpectDie(t, func() { NewOptions(spec) })
 }
 
+func TestPositionals(t *testing.T) {
+	s := NewOptions("TestPositionals\n--\nr,recursive doc\n--\nsrc+ files to copy\ndest where to put them\n")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"a", "-r", "b", "c"})
+	if diff := cmp.Diff([]string{"a", "b"}, opt.Args("src")); diff != "" {
+		t.Errorf(`opt.Args("src") diff (-want+got):\n%s`, diff)
+	}
+	if got, want := opt.Arg("dest"), "c"; got != want {
+		t.Errorf(`opt.Arg("dest")=%q, want=%q`, got, want)
+	}
+	if got, want := s.ArgSynopsis(), "src... dest"; got != want {
+		t.Errorf("ArgSynopsis()=%q, want=%q", got, want)
+	}
+	if want := "\n  src...  files to copy\n  dest  where to put them\n"; !strings.Contains(s.Usage, want) {
+		t.Errorf("Usage=%q, want it to contain %q", s.Usage, want)
+	}
+}
+
+func TestPositionals_leftover(t *testing.T) {
+	s := NewOptions("TestPositionals_leftover\n--\nr,recursive doc\n--\nsrc+ files to copy\ndest where to put them\n")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"a", "-r", "--", "-file", "dest"})
+	if diff := cmp.Diff([]string{"a", "-file"}, opt.Args("src")); diff != "" {
+		t.Errorf(`opt.Args("src") diff (-want+got):\n%s`, diff)
+	}
+	if got, want := opt.Arg("dest"), "dest"; got != want {
+		t.Errorf(`opt.Arg("dest")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"a"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestPositionals_optional(t *testing.T) {
+	s := NewOptions("TestPositionals_optional\n--\n--\nsrc\ndest?\nrest*")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"a"})
+	if got, want := opt.Arg("src"), "a"; got != want {
+		t.Errorf(`opt.Arg("src")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Arg("dest"), ""; got != want {
+		t.Errorf(`opt.Arg("dest")=%q, want=%q`, got, want)
+	}
+	if got := opt.Args("rest"); len(got) != 0 {
+		t.Errorf(`opt.Args("rest")=%q, want none`, got)
+	}
+	opt = s.Parse([]string{"a", "b", "c", "d"})
+	if diff := cmp.Diff([]string{"c", "d"}, opt.Args("rest")); diff != "" {
+		t.Errorf(`opt.Args("rest") diff (-want+got):\n%s`, diff)
+	}
+}
+
+func TestPositionals_count(t *testing.T) {
+	s := NewOptions("TestPositionals_count\n--\n--\nsrc\ndest")
+	var i int
+	s.Exit = func(code int) { i = code }
+	s.ErrorWriter = devNull{}
+	for _, args := range [][]string{{"a"}, {"a", "b", "c"}} {
+		i = 0
+		s.Parse(args)
+		if i == 0 {
+			t.Errorf("Parse(%q): expected failure with nonzero code, got=0", args)
+		}
+	}
+}
+
+func TestNargs(t *testing.T) {
+	s := NewOptions("TestNargs\n--\np,point=X,Y doc [0 0]\nrename=FROM,TO doc\nfile=FILE doc")
+	s.Exit = exitToPanic
+	if got, want := s.Usage, "TestNargs\n\n  -p, --point X Y  doc [0 0]\n  --rename FROM TO  doc\n  --file=FILE  doc\n"; got != want {
+		t.Errorf("s.Usage=%q, want=%q", got, want)
+	}
+	opt := s.Parse([]string{"--rename", "a b", "c", "extra", "--file", "-"})
+	if diff := cmp.Diff([]string{"0", "0"}, opt.GetList("point")); diff != "" {
+		t.Errorf("default point diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a b", "c"}, opt.GetList("rename")); diff != "" {
+		t.Errorf("rename diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"-"}, opt.GetList("file")); diff != "" {
+		t.Errorf("file diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([][]string{{"--rename", "a b", "c"}, {"--file", "-"}}, opt.Flags); diff != "" {
+		t.Errorf("flags diff (-want+got):\n%s", diff)
+	}
+
+	opt = s.Parse([]string{"-p=1", "2"})
+	if got, want := opt.Get("point"), "1 2"; got != want {
+		t.Errorf(`opt.Get("point")=%q, want=%q`, got, want)
+	}
+
+	var out bytes.Buffer
+	s.ErrorWriter = &out
+	s.Exit = func(int) {}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--point", "1"}, "Missing argument: point\n"},
+		{[]string{"--point", "1", "--rename"}, "Missing argument: point: found flag --rename\n"},
+		{[]string{"-p", "1", "--", "2"}, "Missing argument: p\n"},
+	} {
+		out.Reset()
+		s.Parse(tc.args)
+		if got := out.String(); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("Parse(%q) error output=%q, want prefix %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestNumericOption(t *testing.T) {
+	s := NewOptions("TestNumericOption\n--\nn,lines=N# doc [10]\nc,count doc\noffset= doc")
+	s.Exit = exitToPanic
+	if got, want := s.Usage, "TestNumericOption\n\n  -n, --lines=N, -NUM  doc [10]\n  -c, --count  doc\n  --offset=  doc\n"; got != want {
+		t.Errorf("s.Usage=%q, want=%q", got, want)
+	}
+	opt := s.Parse([]string{"-5", "-c", "--offset", "-12"})
+	if got, want := opt.Get("lines"), "5"; got != want {
+		t.Errorf(`opt.Get("lines")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Get("offset"), "-12"; got != want {
+		t.Errorf(`opt.Get("offset")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([][]string{{"-5", "5"}, {"-c"}, {"--offset", "-12"}}, opt.Flags); diff != "" {
+		t.Errorf("flags diff (-want+got):\n%s", diff)
+	}
+	if got, want := opt.Source("lines").Flag, "-5"; got != want {
+		t.Errorf(`opt.Source("lines").Flag=%q, want=%q`, got, want)
+	}
+
+	want := []SpecError{
+		{Line: 4, Column: 4, Msg: "both aaa and bbb are given as -NUM", Fatal: true},
+		{Line: 5, Column: 4, Msg: "option ccc must take a single argument to be given as -NUM", Fatal: true},
+	}
+	if diff := cmp.Diff(want, ValidateSpec("synopsis\n--\naaa=# doc\nbbb=# doc\nccc# doc")); diff != "" {
+		t.Errorf("ValidateSpec diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestNegativeNumbers(t *testing.T) {
+	s := NewOptions("TestNegativeNumbers\n--\noffset= doc\npoint=X,Y doc\nv,verbose doc").SetUnknownOptionsFatal(false)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"--offset", "-5", "-1.5", "--point", "-1", "-2", "--unk", "-3", "-v", "-.5"})
+	if got, want := opt.Get("offset"), "-5"; got != want {
+		t.Errorf(`opt.Get("offset")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"-1", "-2"}, opt.GetList("point")); diff != "" {
+		t.Errorf("point diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"-1.5", "-.5"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"--unk", "-3"}, opt.Flags[2]); diff != "" {
+		t.Errorf("unknown flag diff (-want+got):\n%s", diff)
+	}
+
+	// With a digit short name, "-1" is that option.
+	s = NewOptions("TestNegativeNumbers\n--\n1,one doc")
+	s.Exit = exitToPanic
+	opt = s.Parse([]string{"-1"})
+	if got, want := opt.GetInt("one"), 1; got != want {
+		t.Errorf(`opt.GetInt("one")=%d, want=%d`, got, want)
+	}
+}
+
+func TestToggles(t *testing.T) {
+	s := NewOptions("TestToggles\n--\nx,xrm+ doc [1]\nv,verbose doc").SetUnknownOptionsFatal(false)
+	s.Exit = exitToPanic
+	if got, want := s.Usage, "TestToggles\n\n  -x/+x, --xrm/+xrm  doc [1]\n  -v, --verbose  doc\n"; got != want {
+		t.Errorf("s.Usage=%q, want=%q", got, want)
+	}
+	opt := s.Parse([]string{"+x", "+v", "+other"})
+	if got, want := opt.GetBool("xrm"), false; got != want {
+		t.Errorf(`opt.GetBool("xrm")=%t, want=%t`, got, want)
+	}
+	if diff := cmp.Diff([]string{"+v", "+other"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"+xrm", "+v", "+other"}, opt.Argv()); diff != "" {
+		t.Errorf("opt.Argv() diff (-want+got):\n%s", diff)
+	}
+	opt = s.Parse([]string{"+xrm", "-x"})
+	if got, want := opt.GetInt("xrm"), 1; got != want {
+		t.Errorf(`opt.GetInt("xrm")=%d, want=%d`, got, want)
+	}
+
+	var got []string
+	s.ParseCallback = func(spec *OptionSpec, option string, argument *string) {
+		got = append(got, option)
+	}
+	s.Parse([]string{"-x", "+xrm"})
+	if diff := cmp.Diff([]string{"x", "+xrm"}, got); diff != "" {
+		t.Errorf("callback options diff (-want+got):\n%s", diff)
+	}
+
+	if errs := ValidateSpec("synopsis\n--\nfoo=+ doc"); len(errs) != 1 || !errs[0].Fatal {
+		t.Errorf("ValidateSpec(toggle with argument)=%v, want one fatal error", errs)
+	}
+}
+
+func TestSingleDashLong(t *testing.T) {
+	spec := "TestSingleDashLong\n--\nv,verbose doc\nq doc\ni,input-encoding= doc\nD,define=K=V doc"
+	s := NewOptions(spec).SetSingleDashLong(true)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-verbose", "-input-encoding=latin1", "-define=a=b", "-Dc=d", "-v", "--verbose"})
+	if got, want := opt.GetInt("verbose"), 3; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if got, want := opt.Get("input-encoding"), "latin1"; got != want {
+		t.Errorf(`opt.Get("input-encoding")=%q, want=%q`, got, want)
+	}
+	if diff := cmp.Diff([]string{"a", "c"}, opt.GetMap("define").Keys()); diff != "" {
+		t.Errorf("define keys diff (-want+got):\n%s", diff)
+	}
+
+	var out bytes.Buffer
+	s.ErrorWriter = &out
+	s.Exit = func(int) {}
+	s.Parse([]string{"-vq"})
+	if got, want := out.String(), "Unkown option: vq\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+
+	s.Exit = exitToPanic
+	opt = s.SetClusterFallback(true).Parse([]string{"-vq", "-verbose"})
+	if got, want := opt.GetInt("verbose"), 2; got != want {
+		t.Errorf(`with fallback: opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if got, want := opt.GetInt("q"), 1; got != want {
+		t.Errorf(`with fallback: opt.GetInt("q")=%d, want=%d`, got, want)
+	}
+}
+
+func TestHiddenOptions(t *testing.T) {
+	s := NewOptions("TestHiddenOptions\n--\na,bbb doc a\nddd*= doc d\neee=* doc e\nfff* doc f")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"--ddd", "x", "--eee=y", "--fff"})
+	if got, want := opt.Get("ddd"), "x"; got != want {
+		t.Errorf(`opt.Get("ddd")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Get("eee"), "y"; got != want {
+		t.Errorf(`opt.Get("eee")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.GetBool("fff"), true; got != want {
+		t.Errorf(`opt.GetBool("fff")=%t, want=%t`, got, want)
+	}
+	if want := "TestHiddenOptions\n\n  -a, --bbb  doc a\n"; s.Usage != want {
+		t.Errorf("Usage=%q, want=%q", s.Usage, want)
+	}
+	if want := "TestHiddenOptions\n\n  -a, --bbb  doc a\n  --ddd=  doc d\n  --eee=  doc e\n  --fff  doc f\n"; s.FullUsage != want {
+		t.Errorf("FullUsage=%q, want=%q", s.FullUsage, want)
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+	s := NewOptions("TestDeprecate\n--\na,old-ccc,ccc= doc c\nd,ddd= doc d\neee doc e")
+	s.Exit = exitToPanic
+	var warnings bytes.Buffer
+	s.WarningWriter = &warnings
+	s.Deprecate("old-ccc", "ccc", "").
+		Deprecate("charset", "ccc", "going away").
+		Deprecate("ddd", "ccc", "").
+		Deprecate("eee", "", "")
+	if want := "TestDeprecate\n\n  -a, --ccc=  doc c\n"; s.Usage != want {
+		t.Errorf("Usage=%q, want=%q", s.Usage, want)
+	}
+	opt := s.Parse([]string{"--old-ccc", "1", "--charset=2", "-d", "3", "-a", "4", "--eee"})
+	if got, want := opt.Get("ccc"), "4"; got != want {
+		t.Errorf(`opt.Get("ccc")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.GetInt("eee"), 1; got != want {
+		t.Errorf(`opt.GetInt("eee")=%d, want=%d`, got, want)
+	}
+	want := []Deprecation{
+		{Flag: "--old-ccc", Replacement: "ccc"},
+		{Flag: "--charset", Replacement: "ccc", Message: "going away"},
+		{Flag: "-d", Replacement: "ccc"},
+		{Flag: "--eee"},
+	}
+	if diff := cmp.Diff(want, opt.Deprecated); diff != "" {
+		t.Errorf("opt.Deprecated diff (-want+got):\n%s", diff)
+	}
+	wantWarnings := "Warning: --old-ccc is deprecated; use --ccc instead\n" +
+		"Warning: --charset is deprecated; use --ccc instead: going away\n" +
+		"Warning: -d is deprecated; use --ccc instead\n" +
+		"Warning: --eee is deprecated\n"
+	if got := warnings.String(); got != wantWarnings {
+		t.Errorf("warnings=%q, want=%q", got, wantWarnings)
+	}
+}
+
 func TestGetAll(t *testing.T) {
 	if diff := cmp.Diff(
 		GetAll("elk", [][]string{[]string{"foo", "aaa"}, []string{"bar"}, []string{"foo", "bbb"}}),
@@ -321,6 +647,18 @@ func TestClustering_missingArg(t *testing.T) {
 
 }
 
+func TestClustering_extra(t *testing.T) {
+	s := NewOptions("TestClustering_extra\n--\nv,verbose doc\nq,quiet doc")
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"-vq", "extra"})
+	if got, want := opt.GetInt("verbose"), 1; got != want {
+		t.Errorf(`opt.GetInt("verbose")=%d, want=%d`, got, want)
+	}
+	if diff := cmp.Diff([]string{"extra"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+}
+
 func exitToPanic(code int) {
 	panic(fmt.Sprintf("exiting with code: %d", code))
 }
@@ -0,0 +1,21 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Command optionsvet checks option names used with the options package
+// against constant specs. See package optionscheck for details. Install it
+// with
+//
+//	go install github.com/gaal/go-options/options/optionscheck/cmd/optionsvet@latest
+//
+// It can be run directly or by go vet:
+//
+//	go vet -vettool=$(which optionsvet) ./...
+package main
+
+import (
+	"github.com/gaal/go-options/options/optionscheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() { singlechecker.Main(optionscheck.Analyzer) }
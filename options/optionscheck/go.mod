@@ -0,0 +1,18 @@
+module github.com/gaal/go-options/options/optionscheck
+
+go 1.23.0
+
+require (
+	github.com/gaal/go-options v0.1.0
+	golang.org/x/tools v0.34.0
+)
+
+require (
+	golang.org/x/mod v0.25.0 // indirect
+	golang.org/x/sync v0.15.0 // indirect
+)
+
+// Build against the library in this repository while working on both.
+// Outside this module the replacement is ignored and v0.1.0 is used, so the
+// library must be tagged before the analyzer is.
+replace github.com/gaal/go-options => ../..
@@ -0,0 +1,320 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package optionscheck defines an Analyzer that checks option names used
+// with the options package against the spec they were parsed with.
+//
+// Options must be looked up by their canonical name: opt.Get("i") panics at
+// runtime if "i" is merely an alias. When the spec passed to
+// options.NewOptions is a constant, this analyzer finds such mistakes at
+// build time. It follows the OptionSpec and Options values through
+// variables within a package and reports Get, GetInt, GetBool, GetList,
+// GetMap, Have, Source and Set calls naming an alias or an unknown option,
+// as well as Arg and Args calls naming an undeclared positional argument. It
+// also reports options.GetAll calls whose flag lacks the leading dashes it
+// needs.
+package optionscheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"github.com/gaal/go-options/options"
+	"golang.org/x/tools/go/analysis"
+)
+
+const optionsPath = "github.com/gaal/go-options/options"
+
+var Analyzer = &analysis.Analyzer{
+	Name: "optionscheck",
+	Doc:  "check option names against constant options specs",
+	Run:  run,
+}
+
+// checker follows specs through one package.
+type checker struct {
+	pass     *analysis.Pass
+	specs    map[ast.Expr]*options.OptionSpec // Parsed specs, by NewOptions call
+	specVars map[types.Object]*options.OptionSpec
+	optVars  map[types.Object]*options.OptionSpec
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	c := &checker{
+		pass:     pass,
+		specs:    make(map[ast.Expr]*options.OptionSpec),
+		specVars: make(map[types.Object]*options.OptionSpec),
+		optVars:  make(map[types.Object]*options.OptionSpec),
+	}
+	// Variables may be used before they are assigned in source order, e.g.
+	// package-level ones, so look at assignments twice.
+	for i := 0; i < 2; i++ {
+		for _, f := range pass.Files {
+			ast.Inspect(f, c.assignments)
+		}
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, c.check)
+	}
+	return nil, nil
+}
+
+// assignments records variables holding known specs or parse results.
+func (c *checker) assignments(n ast.Node) bool {
+	record := func(lhs []*ast.Ident, rhs []ast.Expr) {
+		if len(lhs) != len(rhs) {
+			return
+		}
+		for i, id := range lhs {
+			obj := c.pass.TypesInfo.ObjectOf(id)
+			if obj == nil {
+				continue
+			}
+			if spec := c.specOf(rhs[i]); spec != nil {
+				c.assign(c.specVars, obj, spec)
+			} else if spec := c.optionsOf(rhs[i]); spec != nil {
+				c.assign(c.optVars, obj, spec)
+			}
+		}
+	}
+	switch n := n.(type) {
+	case *ast.AssignStmt:
+		var lhs []*ast.Ident
+		for _, e := range n.Lhs {
+			id, _ := e.(*ast.Ident)
+			lhs = append(lhs, id)
+		}
+		for i, id := range lhs {
+			if id == nil {
+				lhs[i] = ast.NewIdent("_")
+			}
+		}
+		record(lhs, n.Rhs)
+	case *ast.ValueSpec:
+		record(n.Names, n.Values)
+	case *ast.CallExpr:
+		c.specOf(n) // Applies Deprecate calls to tracked specs.
+	}
+	return true
+}
+
+// assign notes that obj holds a value related to spec. Variables assigned
+// values from different specs are not tracked.
+func (c *checker) assign(vars map[types.Object]*options.OptionSpec, obj types.Object, spec *options.OptionSpec) {
+	if prev, ok := vars[obj]; ok && prev != spec {
+		spec = nil
+	}
+	vars[obj] = spec
+}
+
+// specOf returns the spec e evaluates to, if it is known.
+func (c *checker) specOf(e ast.Expr) *options.OptionSpec {
+	switch e := unparen(e).(type) {
+	case *ast.Ident:
+		return c.specVars[c.pass.TypesInfo.ObjectOf(e)]
+	case *ast.CallExpr:
+		fn := c.callee(e)
+		if fn == nil {
+			return nil
+		}
+		if fn.Name() == "NewOptions" && recvName(fn) == "" {
+			return c.newOptions(e)
+		}
+		if recvName(fn) != "OptionSpec" {
+			return nil
+		}
+		sel, ok := unparen(e.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		spec := c.specOf(sel.X)
+		if spec == nil {
+			return nil
+		}
+		if fn.Name() == "Deprecate" {
+			args := c.stringArgs(e)
+			if len(args) != 3 {
+				return nil
+			}
+			func() {
+				defer func() { recover() }() // Not our problem here.
+				spec.Deprecate(args[0], args[1], args[2])
+			}()
+		}
+		if res := fn.Type().(*types.Signature).Results(); res.Len() == 1 && isNamed(res.At(0).Type(), "OptionSpec") {
+			return spec
+		}
+	}
+	return nil
+}
+
+// newOptions parses the spec given to a NewOptions call, if it is constant.
+func (c *checker) newOptions(call *ast.CallExpr) *options.OptionSpec {
+	if spec, ok := c.specs[call]; ok {
+		return spec
+	}
+	var spec *options.OptionSpec
+	if args := c.stringArgs(call); len(args) == 1 {
+		func() {
+			defer func() { recover() }() // Bad specs are for the spec linter.
+			spec = options.NewOptions(args[0])
+		}()
+	}
+	c.specs[call] = spec
+	return spec
+}
+
+// optionsOf returns the spec the Options e evaluates to were parsed with,
+// if it is known.
+func (c *checker) optionsOf(e ast.Expr) *options.OptionSpec {
+	switch e := unparen(e).(type) {
+	case *ast.Ident:
+		return c.optVars[c.pass.TypesInfo.ObjectOf(e)]
+	case *ast.UnaryExpr:
+		return c.optionsOf(e.X)
+	case *ast.StarExpr:
+		return c.optionsOf(e.X)
+	case *ast.CallExpr:
+		if fn := c.callee(e); fn != nil && fn.Name() == "Parse" && recvName(fn) == "OptionSpec" {
+			return c.specOf(e.Fun.(*ast.SelectorExpr).X)
+		}
+	}
+	return nil
+}
+
+// check reports misuses of option names.
+func (c *checker) check(n ast.Node) bool {
+	call, ok := n.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return true
+	}
+	fn := c.callee(call)
+	if fn == nil {
+		return true
+	}
+	name, ok := c.stringConst(call.Args[0])
+	if !ok {
+		return true
+	}
+	if fn.Name() == "GetAll" && recvName(fn) == "" {
+		if !strings.HasPrefix(name, "-") {
+			c.pass.Reportf(call.Args[0].Pos(), "GetAll needs the flag as presented, with dashes: %q", prettyFlag(name))
+		}
+		return true
+	}
+	if recvName(fn) != "Options" {
+		return true
+	}
+	spec := c.optionsOf(call.Fun.(*ast.SelectorExpr).X)
+	if spec == nil {
+		return true
+	}
+	switch fn.Name() {
+	case "Get", "GetInt", "GetBool", "GetList", "GetMap", "Have", "Source", "Set":
+		switch canonical := spec.GetCanonical(name); canonical {
+		case name:
+		case "":
+			c.pass.Reportf(call.Args[0].Pos(), "%s of unknown option %q", fn.Name(), name)
+		default:
+			c.pass.Reportf(call.Args[0].Pos(), "%s of %q, which is an alias; use the canonical name %q",
+				fn.Name(), name, canonical)
+		}
+	case "Arg", "Args":
+		for _, arg := range spec.ArgInfo() {
+			if arg.Name == name {
+				return true
+			}
+		}
+		c.pass.Reportf(call.Args[0].Pos(), "%s of undeclared argument %q", fn.Name(), name)
+	}
+	return true
+}
+
+// callee returns the function or method of the options package called, if
+// that is what is called.
+func (c *checker) callee(call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch fun := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, ok := c.pass.TypesInfo.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != optionsPath {
+		return nil
+	}
+	return fn
+}
+
+// stringArgs returns the arguments of call if they are all string constants.
+func (c *checker) stringArgs(call *ast.CallExpr) []string {
+	var out []string
+	for _, arg := range call.Args {
+		s, ok := c.stringConst(arg)
+		if !ok {
+			return nil
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (c *checker) stringConst(e ast.Expr) (string, bool) {
+	tv, ok := c.pass.TypesInfo.Types[e]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// recvName returns the name of the receiver type of a method, or the empty
+// string for functions.
+func recvName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return ""
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return ""
+}
+
+// isNamed reports whether t is a pointer to the named type of the options
+// package.
+func isNamed(t types.Type, name string) bool {
+	p, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := p.Elem().(*types.Named)
+	return ok && named.Obj().Name() == name && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == optionsPath
+}
+
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+func prettyFlag(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return fmt.Sprintf("--%s", name)
+}
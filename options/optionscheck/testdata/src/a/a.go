@@ -0,0 +1,46 @@
+package a
+
+import (
+	"os"
+
+	"github.com/gaal/go-options/options"
+)
+
+const mySpec = `
+Usage: a [OPTIONS] src
+--
+i,input-encoding=     charset input is encoded in [utf-8]
+v,verbose             be verbose
+old-name=             renamed
+author=               authors you like
+--
+src                   file to read
+`
+
+var spec = options.NewOptions(mySpec).SetResponseFiles(true)
+
+func main() {
+	opt := spec.Parse(os.Args[1:])
+	opt.Get("input-encoding")
+	opt.Get("i") // want `Get of "i", which is an alias; use the canonical name "input-encoding"`
+	opt.GetInt("verbose")
+	opt.GetBool("v")       // want `GetBool of "v", which is an alias; use the canonical name "verbose"`
+	opt.Have("frobnicate") // want `Have of unknown option "frobnicate"`
+	opt.GetList("author")
+	opt.Get("old-name") // want `Get of "old-name", which is an alias; use the canonical name "author"`
+	opt.Arg("src")
+	opt.Arg("dest") // want `Arg of undeclared argument "dest"`
+	options.GetAll("--author", opt.Flags)
+	options.GetAll("author", opt.Flags) // want `GetAll needs the flag as presented, with dashes: "--author"`
+
+	p := &opt
+	p.Get("verbose")
+	p.Get("v") // want `Get of "v", which is an alias`
+
+	other := options.NewOptions(os.Getenv("SPEC")).Parse(nil)
+	other.Get("anything")
+}
+
+func init() {
+	spec.Deprecate("old-name", "author", "")
+}
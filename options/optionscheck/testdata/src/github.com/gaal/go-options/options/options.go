@@ -0,0 +1,25 @@
+// Package options is a stand-in for the real package, declaring just enough
+// for the test code to type check.
+package options
+
+type Options struct {
+	Flags [][]string
+	Extra []string
+}
+
+func (o *Options) Get(flag string) string       { return "" }
+func (o *Options) GetInt(flag string) int       { return 0 }
+func (o *Options) GetBool(flag string) bool     { return false }
+func (o *Options) GetList(flag string) []string { return nil }
+func (o *Options) Have(flag string) bool        { return false }
+func (o *Options) Arg(name string) string       { return "" }
+func (o *Options) Args(name string) []string    { return nil }
+
+func GetAll(flag string, flags [][]string) []string { return nil }
+
+type OptionSpec struct{}
+
+func NewOptions(spec string) *OptionSpec                                      { return nil }
+func (s *OptionSpec) SetResponseFiles(val bool) *OptionSpec                   { return s }
+func (s *OptionSpec) Deprecate(name, replacement, message string) *OptionSpec { return s }
+func (s *OptionSpec) Parse(args []string) Options                             { return Options{} }
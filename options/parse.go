@@ -0,0 +1,453 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parser holds the state of a single call to Parse. Scanning arguments does
+// not allocate; only recording the results, and the values attached to flags
+// with "=", which callbacks may keep, do.
+type parser struct {
+	s     *OptionSpec
+	opt   *Options
+	args  []string
+	where []argPos // Positions of args, if read from response files
+	i     int      // Index in args of the argument being parsed
+	buf   []string // Backing store for the entries of opt.Flags
+	ctx   ParseContext
+
+	stopAtFirstExtra bool // StopAtFirstExtra, or POSIXLY_CORRECT is set and not ignored
+}
+
+// flagToken is a command line argument that looks like a flag, that is,
+// "-name", "--name", "-name=value" or "--name=value". Its fields are
+// substrings of the argument.
+type flagToken struct {
+	flag     string // The flag as presented, without any value
+	dash     string // "-", "--", or "+" for a toggle turned off
+	name     string // The flag without dashes
+	value    string // The value after "=", if hasValue
+	hasValue bool
+	numeric  bool // Whether this is -NUM, with name the option it stands for
+	long     bool // Whether this is a long option with a single dash
+}
+
+// scanFlag splits arg into its parts, if it looks like a flag. Names consist
+// of letters, digits, underscores and dashes.
+func scanFlag(arg string) (tok flagToken, ok bool) {
+	if len(arg) < 2 || arg[0] != '-' {
+		return tok, false
+	}
+	dash := 1
+	if arg[1] == '-' && len(arg) > 2 {
+		dash = 2
+	}
+	end := dash
+	for end < len(arg) && (isWordByte(arg[end]) || arg[end] == '-') {
+		end++
+	}
+	if end == dash || (end < len(arg) && arg[end] != '=') {
+		return tok, false
+	}
+	tok = flagToken{flag: arg[:end], dash: arg[:dash], name: arg[dash:end]}
+	if end < len(arg) {
+		tok.value, tok.hasValue = arg[end+1:], true
+	}
+	return tok, true
+}
+
+// scanToggle recognizes +name for a toggle option.
+func (s *OptionSpec) scanToggle(arg string) (tok flagToken, ok bool) {
+	if len(arg) < 2 || arg[0] != '+' || !s.toggles[s.aliases[arg[1:]]] {
+		return tok, false
+	}
+	return flagToken{flag: arg, dash: "+", name: arg[1:]}, true
+}
+
+// scanNumeric recognizes -NUM, if the spec has an option for it.
+func (s *OptionSpec) scanNumeric(arg string) (tok flagToken, ok bool) {
+	if s.numeric == "" || len(arg) < 2 || arg[0] != '-' {
+		return tok, false
+	}
+	for i := 1; i < len(arg); i++ {
+		if arg[i] < '0' || arg[i] > '9' {
+			return tok, false
+		}
+	}
+	return flagToken{flag: arg, dash: "-", name: s.numeric, value: arg[1:], hasValue: true, numeric: true}, true
+}
+
+// isNegativeNumber reports whether arg is a negative number rather than a
+// flag, which it is unless the spec has a -NUM option or digit short names.
+func (s *OptionSpec) isNegativeNumber(arg string) bool {
+	if !s.negatives || len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	if c := arg[1]; c != '.' && (c < '0' || c > '9') {
+		return false // Not "-Inf" or "-NaN".
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// scan recognizes any kind of flag the spec accepts.
+func (s *OptionSpec) scan(arg string) (tok flagToken, ok bool) {
+	tok, ok = s.scanToggle(arg)
+	if !ok {
+		tok, ok = s.scanNumeric(arg)
+	}
+	if !ok {
+		tok, ok = s.scanMapFlag(arg)
+	}
+	if !ok {
+		tok, ok = scanFlag(arg)
+	}
+	return tok, ok && !s.isNegativeNumber(arg)
+}
+
+// pos returns the position of args[i].
+func (p *parser) pos(i int) argPos {
+	if p.where == nil {
+		return argPos{index: i}
+	}
+	return p.where[i]
+}
+
+// usageError reports a problem with the argument at pos.
+func (p *parser) usageError(pos argPos, msg string) {
+	p.s.PrintUsageAndExit(posPrefix(pos) + msg)
+}
+
+// run parses all the arguments. It returns false if a callback stopped it.
+func (p *parser) run() bool {
+	s, opt := p.s, p.opt
+	for ; p.i < len(p.args); p.i++ { // Can't use range because we may bump i.
+		arg := p.args[p.i]
+		if arg == "--" {
+			opt.Leftover = append(opt.Leftover, p.args[p.i+1:]...)
+			return true
+		}
+		tok, ok := s.scan(arg)
+		if !ok { // This is not a flag.
+			if s.UnknownValuesFatal {
+				panic(posPrefix(p.pos(p.i)) + s.message(MsgUnexpectedArgument, arg) + "\n" + s.Usage)
+			}
+			if p.stopAtFirstExtra {
+				opt.Extra = append(opt.Extra, p.args[p.i:]...)
+				return true
+			}
+			opt.Extra = append(opt.Extra, arg)
+			continue
+		}
+		if !p.flag(tok) {
+			opt.Leftover = append(opt.Leftover, p.args[p.i+1:]...)
+			return false
+		}
+	}
+	return true
+}
+
+// flag handles a flag, consuming its argument if it needs one. It returns
+// false if parsing should stop.
+func (p *parser) flag(tok flagToken) bool {
+	s := p.s
+	at, pos := p.i, p.pos(p.i)
+	canonical, known := s.aliases[tok.name]
+	// With SingleDashLong, -name is a long option, unless it is unknown and
+	// may fall back to being a cluster.
+	tok.long = tok.dash == "-" && s.SingleDashLong && len(tok.name) > 1 && (known || !s.ClusterFallback)
+	clustered := !known && tok.dash == "-" && !tok.long // Possibly clustering, -abc
+	if known {
+		s.warnDeprecated(p.opt, tok.name, tok.dash)
+	} else if clustered {
+		for j, r := range tok.name {
+			s.warnDeprecated(p.opt, tok.name[j:j+utf8.RuneLen(r)], "-")
+		}
+	}
+
+	var n int // Number of arguments to consume
+	switch {
+	case known:
+		n = s.arity(canonical)
+	case tok.hasValue:
+		n = 1
+	case clustered && p.knownCluster(tok.name):
+		_, size := utf8.DecodeLastRuneInString(tok.name)
+		n = s.arity(s.aliases[tok.name[len(tok.name)-size:]])
+	case s.ContextCallback != nil:
+		// Leave it to the callback, which can look ahead.
+	default:
+		// Best effort for unknown flags: we can't tell whether they take
+		// an argument, so guess from the next one.
+		if next := p.i + 1; next < len(p.args) && (!strings.HasPrefix(p.args[next], "-") || s.isNegativeNumber(p.args[next])) {
+			n = 1
+		}
+	}
+	var value *string
+	var values []string
+	switch {
+	case n > 1:
+		values = p.values(tok, n, pos)
+		v := strings.Join(values, " ")
+		value = &v
+	case n == 1 || (tok.hasValue && (s.ContextCallback != nil || s.ParseCallback == nil)):
+		if tok.hasValue {
+			v := tok.value
+			value = &v
+		} else if p.i+1 < len(p.args) {
+			p.i++
+			value = &p.args[p.i]
+		}
+	}
+
+	if s.ContextCallback != nil {
+		p.ctx = ParseContext{
+			Spec:     s,
+			Options:  p.opt,
+			Flag:     tok.flag,
+			Name:     tok.name,
+			Dash:     tok.dash,
+			Index:    pos.index,
+			Value:    value,
+			Values:   values,
+			Attached: tok.hasValue,
+			p:        p,
+			tok:      tok,
+			pos:      pos,
+		}
+		if known {
+			p.ctx.Canonical = canonical
+		}
+		switch err := s.ContextCallback(&p.ctx); {
+		case err == nil:
+		case errors.Is(err, Stop):
+			return false
+		case errors.Is(err, Skip):
+			p.opt.Extra = append(p.opt.Extra, p.args[at])
+			p.i = at
+		default:
+			s.PrintUsageAndExit(p.ctx.Source().String() + ": " + err.Error())
+		}
+		return true
+	}
+	if s.ParseCallback != nil {
+		name := tok.name
+		if tok.dash == "+" {
+			name = "+" + name
+		}
+		s.ParseCallback(s, name, value)
+		return true
+	}
+	p.store(tok, pos, value, values)
+	return true
+}
+
+// values consumes the n arguments of an option that takes several, the
+// first of which may be attached to the flag with "=". None of them may
+// look like a flag.
+func (p *parser) values(tok flagToken, n int, pos argPos) []string {
+	values := make([]string, 0, n)
+	if tok.hasValue {
+		values = append(values, tok.value)
+	}
+	for len(values) < n {
+		if p.i+1 >= len(p.args) || p.args[p.i+1] == "--" {
+			p.usageError(pos, p.s.message(MsgMissingArgument, tok.name))
+			return values // not reached
+		}
+		if _, isFlag := scanFlag(p.args[p.i+1]); isFlag && !p.s.isNegativeNumber(p.args[p.i+1]) {
+			p.usageError(pos, p.s.message(MsgMissingArgumentFlag, tok.name, p.args[p.i+1]))
+			return values // not reached
+		}
+		p.i++
+		values = append(values, p.args[p.i])
+	}
+	return values
+}
+
+// knownCluster reports whether every letter of a cluster names an option.
+func (p *parser) knownCluster(name string) bool {
+	for j, r := range name {
+		if _, known := p.s.aliases[name[j:j+utf8.RuneLen(r)]]; !known {
+			return false
+		}
+	}
+	return true
+}
+
+// store records a flag in Options. This is what Parse does unless a custom
+// callback is set. Options taking several arguments have them in values.
+func (p *parser) store(tok flagToken, pos argPos, value *string, values []string) {
+	s, opt := p.s, p.opt
+	if tok.dash == "-" && len(tok.name) > 1 && !tok.numeric && !tok.long { // Clustering, -abc
+		for j, r := range tok.name {
+			short := tok.name[j : j+utf8.RuneLen(r)]
+			isLast := j+len(short) == len(tok.name)
+			canonical, known := s.aliases[short]
+			if !p.known(known, tok.name, pos) {
+				continue
+			}
+			if s.requiresArg[canonical] {
+				if value == nil || !isLast {
+					p.usageError(pos, p.s.message(MsgMissingArgument, short))
+					return // not reached
+				}
+				if s.isMap(canonical) {
+					p.mapEntry(canonical, short, *value, pos)
+				}
+				p.set(canonical, *value, "-"+short, pos)
+				if values != nil {
+					opt.setList(canonical, values)
+				}
+			} else {
+				if value != nil && isLast {
+					p.usageError(pos, p.s.message(MsgUnexpectedOptionArgument, short, *value))
+					return // not reached
+				}
+				p.count(canonical, short, "-"+short, pos)
+			}
+		}
+	} else if canonical, known := s.aliases[tok.name]; p.known(known, tok.name, pos) {
+		if s.requiresArg[canonical] {
+			if value == nil {
+				p.usageError(pos, p.s.message(MsgMissingArgument, tok.name))
+				return // not reached
+			}
+			if s.isMap(canonical) {
+				p.mapEntry(canonical, tok.name, *value, pos)
+			}
+			p.set(canonical, *value, tok.flag, pos)
+			if values != nil {
+				opt.setList(canonical, values)
+			}
+		} else if tok.dash == "+" {
+			p.set(canonical, strconv.Itoa(s.counters[canonical].clamp(0)), tok.flag, pos)
+		} else if value != nil {
+			p.level(canonical, tok.name, *value, tok.flag, pos)
+		} else {
+			p.count(canonical, tok.name, tok.flag, pos)
+		}
+	}
+	if values != nil {
+		opt.Flags = append(opt.Flags, p.entry(tok.flag, values...))
+	} else if value != nil {
+		opt.Flags = append(opt.Flags, p.entry(tok.flag, *value))
+	} else {
+		opt.Flags = append(opt.Flags, p.entry(tok.flag))
+	}
+}
+
+// entry returns an element for opt.Flags. Entries are carved out of a single
+// allocation, with their capacity limited so appending to one is safe.
+func (p *parser) entry(flag string, values ...string) []string {
+	if cap(p.buf)-len(p.buf) < 1+len(values) {
+		size := 2 * (len(p.args) - p.i)
+		if size < 1+len(values) {
+			size = 1 + len(values)
+		}
+		p.buf = make([]string, 0, size)
+	}
+	n := len(p.buf)
+	p.buf = append(p.buf, flag)
+	p.buf = append(p.buf, values...)
+	return p.buf[n:len(p.buf):len(p.buf)]
+}
+
+// known tells whether to go on with an option, failing if it is unknown and
+// unknown options are fatal.
+func (p *parser) known(known bool, option string, pos argPos) bool {
+	if !known && p.s.UnknownOptionsFatal {
+		p.usageError(pos, p.s.message(MsgUnknownOption, option))
+		return false // not reached
+	}
+	return known
+}
+
+// set records the value of an option.
+func (p *parser) set(canonical, value, flag string, pos argPos) {
+	p.opt.opts[canonical] = value
+	p.opt.sources[canonical] = Source{Kind: SourceArgs, Name: pos.file, Line: pos.line, Index: pos.index, Flag: flag}
+}
+
+// mapEntry adds the argument of a map option to its map, following the
+// spec's KeyPolicy. A map from the spec's default is replaced, not added to.
+// It must be called before set.
+func (p *parser) mapEntry(canonical, name, arg string, pos argPos) {
+	key, val, ok := mapEntry(arg)
+	if !ok {
+		p.usageError(pos, p.s.message(MsgBadArgument, name, arg, p.s.metavars[canonical][0]))
+		return // not reached
+	}
+	m := p.opt.maps[canonical]
+	if m == nil || p.opt.sources[canonical].Kind == SourceDefault {
+		m = &Map{}
+		p.opt.setMap(canonical, m)
+	}
+	if _, dup := m.Get(key); dup {
+		switch p.s.DuplicateKeys {
+		case FirstKeyWins:
+			return
+		case DuplicateKeysFatal:
+			p.usageError(pos, p.s.message(MsgDuplicateKey, name, key))
+			return // not reached
+		}
+	}
+	m.set(key, val)
+}
+
+// count counts another occurrence of an option that takes no argument, or
+// one less if name counts it down. As with GetInt, an empty count is zero,
+// but any other count must be a level, or a panic occurs.
+func (p *parser) count(canonical, name, flag string, pos argPos) {
+	var n int
+	if val := p.opt.opts[canonical]; val != "" {
+		var ok bool
+		if n, ok = parseLevel(val); !ok {
+			panic("Bad integer value for option: " + canonical + ": " + val)
+		}
+	}
+	if p.s.decrements[name] {
+		n--
+	} else {
+		n++
+	}
+	p.set(canonical, strconv.Itoa(p.s.counters[canonical].clamp(n)), flag, pos)
+}
+
+// level sets the count of an option that takes no argument explicitly, as
+// in "--verbose=2".
+func (p *parser) level(canonical, name, value, flag string, pos argPos) {
+	if p.s.decrements[name] {
+		p.usageError(pos, p.s.message(MsgUnexpectedOptionArgument, name, value))
+		return // not reached
+	}
+	n, ok := parseLevel(value)
+	if !ok {
+		p.usageError(pos, p.s.message(MsgBadLevel, name, value))
+		return // not reached
+	}
+	p.set(canonical, strconv.Itoa(p.s.counters[canonical].clamp(n)), flag, pos)
+}
+
+// parseLevel parses the explicit level of an option that takes no argument.
+// As with package flag, "true" and "false" also work.
+func parseLevel(value string) (int, bool) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n, true
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return 0, false
+	}
+	if b {
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,135 @@
+package options
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanFlag(t *testing.T) {
+	for _, tc := range []struct {
+		arg  string
+		want flagToken
+		ok   bool
+	}{
+		{"-a", flagToken{flag: "-a", dash: "-", name: "a"}, true},
+		{"--aaa", flagToken{flag: "--aaa", dash: "--", name: "aaa"}, true},
+		{"--aaa=", flagToken{flag: "--aaa", dash: "--", name: "aaa", hasValue: true}, true},
+		{"-ab=c=d", flagToken{flag: "-ab", dash: "-", name: "ab", value: "c=d", hasValue: true}, true},
+		{"--a-b_c", flagToken{flag: "--a-b_c", dash: "--", name: "a-b_c"}, true},
+		{"---a", flagToken{flag: "---a", dash: "--", name: "-a"}, true},
+		{"-", flagToken{}, false},
+		{"--", flagToken{flag: "--", dash: "-", name: "-"}, true}, // Parse checks for "--" first.
+		{"--=x", flagToken{}, false},
+		{"-a.b", flagToken{}, false},
+		{"foo", flagToken{}, false},
+	} {
+		got, ok := scanFlag(tc.arg)
+		if ok != tc.ok {
+			t.Errorf("scanFlag(%q) ok=%t, want=%t", tc.arg, ok, tc.ok)
+		}
+		if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(flagToken{})); diff != "" {
+			t.Errorf("scanFlag(%q) diff (-want+got):\n%s", tc.arg, diff)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	s := NewOptions("TestParseLevel\n--\nv,verbose{0,2} doc [1]\nq,quiet~verbose doc")
+	s.Exit = exitToPanic
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--verbose=0"}, "0"},
+		{[]string{"--verbose=5"}, "2"},
+		{[]string{"--verbose=false"}, "0"},
+		{[]string{"--verbose=true", "-v"}, "2"},
+		{[]string{"--verbose=F"}, "0"},
+		{[]string{"--verbose=t"}, "1"},
+		{[]string{"--verbose=TRUE", "-q"}, "0"},
+	} {
+		opt := s.Parse(tc.args)
+		if got := opt.Get("verbose"); got != tc.want {
+			t.Errorf("Parse(%q): verbose=%q, want=%q", tc.args, got, tc.want)
+		}
+	}
+
+	var out bytes.Buffer
+	s.Exit = func(int) {}
+	s.ErrorWriter = &out
+	for _, tc := range []struct {
+		arg  string
+		want string
+	}{
+		{"--verbose=yes", "Bad level: verbose: yes\n"},
+		{"--quiet=true", "Unexpected argument: quiet: true\n"},
+	} {
+		out.Reset()
+		s.Parse([]string{tc.arg})
+		if got := out.String(); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("Parse(%q) wrote %q, want prefix %q", tc.arg, got, tc.want)
+		}
+	}
+}
+
+func TestParseCallback_keptValues(t *testing.T) {
+	s := NewOptions("TestParseCallback_keptValues\n--\na= doc")
+	var kept []*string
+	s.ParseCallback = func(spec *OptionSpec, option string, argument *string) {
+		kept = append(kept, argument)
+	}
+	s.Parse([]string{"--a=1", "--a=2", "--unknown=3", "-a", "4"})
+	var got []string
+	for _, v := range kept {
+		got = append(got, *v)
+	}
+	if diff := cmp.Diff([]string{"1", "2", "3", "4"}, got); diff != "" {
+		t.Errorf("kept values diff (-want+got):\n%s", diff)
+	}
+}
+
+func BenchmarkParse_longArgv(b *testing.B) {
+	s := NewOptions("BenchmarkParse_longArgv\n--\na,aaa doc\nb,bbb= doc\nccc doc")
+	s.Exit = exitToPanic
+	var args []string
+	for i := 0; i < 1000; i++ {
+		args = append(args, "--aaa", "-b", strconv.Itoa(i), "--ccc", "--bbb="+strconv.Itoa(i), "extra")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Parse(args)
+	}
+}
+
+func BenchmarkParse_clustering(b *testing.B) {
+	s := NewOptions("BenchmarkParse_clustering\n--\na doc\nb doc\nc doc\nd= doc")
+	s.Exit = exitToPanic
+	args := []string{"-abcabc", "-aabbd", "val", "-cccd=val", "-ab"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Parse(args)
+	}
+}
+
+func BenchmarkParse_manyAliases(b *testing.B) {
+	spec := "BenchmarkParse_manyAliases\n--\n"
+	var args []string
+	for i := 0; i < 200; i++ {
+		n := strconv.Itoa(i)
+		spec += "o" + n + ",opt" + n + ",option" + n + "= doc\n"
+		args = append(args, "--option"+n, n)
+	}
+	s := NewOptions(spec)
+	s.Exit = exitToPanic
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Parse(args)
+	}
+}
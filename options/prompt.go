@@ -0,0 +1,145 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// requireOptions makes sure that the required options have values. If the
+// spec is Interactive, it first prompts for them, provided there is someone
+// to ask: PromptReader was set, or standard input is a terminal.
+func (s *OptionSpec) requireOptions(opt *Options) {
+	if s.Interactive {
+		in, out := s.PromptReader, s.PromptWriter
+		if in == nil && isTerminal(os.Stdin) {
+			in = os.Stdin
+		}
+		if out == nil {
+			out = os.Stderr
+		}
+		if in != nil {
+			if err := s.Prompt(opt, in, out); err != nil {
+				s.PrintUsageAndExit(err.Error())
+				return // not reached
+			}
+		}
+	}
+	for _, info := range s.OptionInfo() {
+		if canonical := info.Canonical(); info.Required && !opt.Have(canonical) {
+			s.PrintUsageAndExit(s.message(MsgMissingOption, prettyFlag(canonical)))
+			return // not reached
+		}
+	}
+}
+
+// Prompt asks for the values of required options that were not given, on
+// the command line or otherwise, writing a prompt with the help text of each
+// to out and reading a line from in. An empty answer keeps the default, if
+// there is one. Answers are checked as arguments on the command line are,
+// and asked for again if they are bad. Answers for secret options are read
+// with PasswordReader, which can turn off the terminal's echo. Without one,
+// Prompt returns an error rather than show a secret typed on a terminal; from
+// other readers, such as pipes, secrets are read as lines. Values are
+// recorded with SourcePrompt. Prompt returns an error if in runs out before
+// all options have values.
+//
+// Parse calls Prompt itself if the spec is Interactive; programs can call it
+// after Parse otherwise.
+func (s *OptionSpec) Prompt(opt *Options, in io.Reader, out io.Writer) error {
+	for _, info := range s.OptionInfo() {
+		canonical := info.Canonical()
+		if kind := opt.Source(canonical).Kind; !info.Required || kind != SourceNone && kind != SourceDefault {
+			continue
+		}
+		help := info.Help
+		if help == "" {
+			help = canonical
+		}
+		for {
+			if info.Secret && s.PasswordReader == nil {
+				if f, ok := in.(*os.File); ok && isTerminal(f) {
+					return errors.New(s.message(MsgSecretEcho, prettyFlag(canonical)))
+				}
+			}
+			fmt.Fprint(out, s.message(MsgPrompt, prettyFlag(canonical), help))
+			var answer string
+			var err error
+			if info.Secret && s.PasswordReader != nil {
+				answer, err = s.PasswordReader(in, out)
+			} else {
+				answer, err = readLine(in)
+			}
+			if err == io.EOF {
+				return errors.New(s.message(MsgMissingOption, prettyFlag(canonical)))
+			} else if err != nil {
+				return err
+			}
+			if answer == "" {
+				if info.HasDefault {
+					break
+				}
+				continue
+			}
+			if err := s.validate(canonical, answer); err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			opt.Set(canonical, answer, Source{Kind: SourcePrompt})
+			break
+		}
+	}
+	return nil
+}
+
+// readLine reads a line answering a prompt. It reads a byte at a time, so
+// that nothing after the line is consumed, e.g. an answer that a
+// PasswordReader reads from a terminal next.
+func readLine(in io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+// isTerminal reports whether f is a terminal, or at least a character
+// device, which is as close as the standard library can tell.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// validate checks a value for an option taking arguments as Parse would
+// check the arguments given on the command line.
+func (s *OptionSpec) validate(canonical, value string) error {
+	switch n := s.arity(canonical); {
+	case s.isMap(canonical):
+		if _, _, ok := mapEntry(value); !ok {
+			return errors.New(s.message(MsgBadArgument, canonical, value, s.metavars[canonical][0]))
+		}
+	case n > 1:
+		if len(strings.Fields(value)) != n {
+			return errors.New(s.message(MsgValueCount, canonical, n))
+		}
+	}
+	return nil
+}
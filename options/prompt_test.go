@@ -0,0 +1,132 @@
+package options
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const promptSpec = `TestPrompt
+--
+u,user=!  user to log in as [root]
+password=!$  password of the user
+D,define=NAME=VALUE!  a definition
+v,verbose  be verbose`
+
+func TestPrompt(t *testing.T) {
+	s := NewOptions(promptSpec).SetInteractive(true)
+	s.Exit = exitToPanic
+	var out bytes.Buffer
+	s.PromptReader = strings.NewReader("\nhunter2\n\nnovalue\nos=linux\n")
+	s.PromptWriter = &out
+
+	opt := s.Parse([]string{"-v"})
+	want := "user to log in as [root] (--user): " +
+		"password of the user (--password): " +
+		"a definition (--define): a definition (--define): " +
+		"Bad argument: define: novalue (want NAME=VALUE)\n" +
+		"a definition (--define): "
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("prompts diff (-want+got):\n%s", diff)
+	}
+	if got, want := opt.Get("user"), "root"; got != want {
+		t.Errorf(`opt.Get("user")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Get("password"), "hunter2"; got != want {
+		t.Errorf(`opt.Get("password")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Source("password").String(), "prompt"; got != want {
+		t.Errorf(`opt.Source("password")=%q, want=%q`, got, want)
+	}
+	if got, _ := opt.GetMap("define").Get("os"); got != "linux" {
+		t.Errorf(`define["os"]=%q, want="linux"`, got)
+	}
+
+	// Options given on the command line are not asked for.
+	out.Reset()
+	s.PromptReader = strings.NewReader("admin\n")
+	opt = s.Parse([]string{"--password=x", "-Da=b"})
+	if got, want := out.String(), "user to log in as [root] (--user): "; got != want {
+		t.Errorf("prompts=%q, want=%q", got, want)
+	}
+	if got, want := opt.Get("user"), "admin"; got != want {
+		t.Errorf(`opt.Get("user")=%q, want=%q`, got, want)
+	}
+}
+
+func TestPrompt_missing(t *testing.T) {
+	var out bytes.Buffer
+	s := NewOptions(promptSpec)
+	s.Exit = func(int) {}
+	s.ErrorWriter = &out
+	s.Parse([]string{"--password=x"})
+	if got, want := out.String(), "Missing option: --define\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+
+	out.Reset()
+	s.SetInteractive(true).PromptReader = strings.NewReader("")
+	s.PromptWriter = devNull{}
+	s.Parse(nil)
+	if got, want := out.String(), "Missing option: --user\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+}
+
+func TestValidateSpec_prompts(t *testing.T) {
+	spec := "synopsis\n--\nforce! doc\nkey$ doc"
+	want := []SpecError{
+		{Line: 3, Column: 6, Msg: "required option force must take an argument", Fatal: true},
+		{Line: 4, Column: 4, Msg: "secret option key must take an argument", Fatal: true},
+	}
+	if diff := cmp.Diff(want, ValidateSpec(spec)); diff != "" {
+		t.Errorf("ValidateSpec diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestPrompt_passwordReader(t *testing.T) {
+	s := NewOptions("TestPrompt_passwordReader\n--\nuser=!  user\npassword=!$  password\nhost=!  host")
+	s.Exit = exitToPanic
+	var secrets int
+	s.PasswordReader = func(in io.Reader, out io.Writer) (string, error) {
+		secrets++
+		return readLine(in) // A terminal would have its echo turned off.
+	}
+	s.SetInteractive(true).PromptReader = strings.NewReader("hunter2\nexample.com\n")
+	s.PromptWriter = devNull{}
+	opt := s.Parse([]string{"--user=admin"})
+	if secrets != 1 {
+		t.Errorf("PasswordReader called %d times, want once", secrets)
+	}
+	if got, want := opt.Get("password"), "hunter2"; got != want {
+		t.Errorf(`opt.Get("password")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.Get("host"), "example.com"; got != want {
+		t.Errorf(`opt.Get("host")=%q, want=%q`, got, want)
+	}
+}
+
+func TestPrompt_secretOnTerminal(t *testing.T) {
+	// The null device is a character device, which is all isTerminal sees.
+	tty, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tty.Close()
+	if !isTerminal(tty) {
+		t.Skip(os.DevNull + " is not a character device")
+	}
+	s := NewOptions(promptSpec)
+	s.Exit = func(int) {} // Parse fails without --password, but goes on.
+	s.ErrorWriter = devNull{}
+	opt := s.Parse([]string{"--user=admin", "-Da=b"})
+	err = s.Prompt(&opt, tty, devNull{})
+	if got, want := fmt.Sprint(err), "Cannot ask for --password without showing it; give it on the command line"; got != want {
+		t.Errorf("Prompt error=%q, want=%q", got, want)
+	}
+}
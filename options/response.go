@@ -0,0 +1,211 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultMaxResponseDepth is how deeply response files may include one
+// another unless OptionSpec.MaxResponseDepth says otherwise.
+const DefaultMaxResponseDepth = 10
+
+// expandResponseFiles replaces every "@path" argument with the arguments
+// read from path, recursively. "@@text" stands for the literal argument
+// "@text". Expansion stops at "--"; everything after it is left alone.
+//
+// The second return value has the position of each argument.
+func (s *OptionSpec) expandResponseFiles(args []string) ([]string, []argPos, error) {
+	maxDepth := s.MaxResponseDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxResponseDepth
+	}
+	var (
+		out   []string
+		where []argPos
+		done  bool // Seen "--".
+	)
+	var expand func(args []string, pos []argPos, depth int) error
+	expand = func(args []string, pos []argPos, depth int) error {
+		for i, arg := range args {
+			switch {
+			case done || arg == "--":
+				done = true
+			case strings.HasPrefix(arg, "@@"):
+				arg = arg[1:]
+			case strings.HasPrefix(arg, "@") && len(arg) > 1:
+				if depth >= maxDepth {
+					return errors.New(posPrefix(pos[i]) + s.message(MsgResponseFileDepth, arg[1:]))
+				}
+				sub, lines, err := s.readResponseFile(arg[1:])
+				if err != nil {
+					return fmt.Errorf("%s%v", posPrefix(pos[i]), err)
+				}
+				subPos := make([]argPos, len(sub))
+				for j, line := range lines {
+					subPos[j] = argPos{index: pos[i].index, file: arg[1:], line: line}
+				}
+				if err := expand(sub, subPos, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			out = append(out, arg)
+			where = append(where, pos[i])
+		}
+		return nil
+	}
+	if err := expand(args, argPositions(len(args)), 0); err != nil {
+		return nil, nil, err
+	}
+	return out, where, nil
+}
+
+// argPos is the position of an argument given to Parse.
+type argPos struct {
+	index int    // Index in the arguments given to Parse
+	file  string // Response file the argument was read from, if any
+	line  int    // Line in file
+}
+
+// argPositions returns the positions of n arguments not read from response
+// files.
+func argPositions(n int) []argPos {
+	pos := make([]argPos, n)
+	for i := range pos {
+		pos[i].index = i
+	}
+	return pos
+}
+
+// readResponseFile splits the contents of a response file into arguments,
+// as described for ResponseFileLines. The second return value has the line
+// number of each argument.
+func (s *OptionSpec) readResponseFile(name string) ([]string, []int, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, nil, errors.New(s.message(MsgResponseFileRead, err))
+	}
+	if s.ResponseFileLines {
+		args, lines := splitLines(data)
+		return args, lines, nil
+	}
+	return s.splitWords(name, data)
+}
+
+// splitLines takes every line of a response file as an argument, verbatim.
+// Empty lines are skipped.
+func splitLines(data []byte) ([]string, []int) {
+	var (
+		args  []string
+		lines []int
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			args = append(args, line)
+			lines = append(lines, i+1)
+		}
+	}
+	return args, lines
+}
+
+// splitWords splits the contents of a response file into arguments
+// separated by whitespace, including newlines. Single and double quotes and
+// backslashes work as in the shell, without any expansions. Lines starting
+// with "#" are comments.
+func (s *OptionSpec) splitWords(name string, data []byte) ([]string, []int, error) {
+	var (
+		args      []string
+		lines     []int
+		word      strings.Builder
+		inWord    bool
+		quote     byte // 0, '\'' or '"'.
+		escaped   bool
+		line      = 1
+		wordLine  int
+		lineStart = true
+	)
+	startWord := func() {
+		if !inWord {
+			inWord = true
+			wordLine = line
+		}
+	}
+	// Every delimiter is ASCII, so bytes can be copied through as they are,
+	// even if the file is not UTF-8.
+	for j := 0; j < len(data); j++ {
+		c := data[j]
+		switch {
+		case escaped:
+			escaped = false
+			if c != '\n' { // Backslash-newline is a line continuation.
+				word.WriteByte(c)
+			}
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case quote == '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && j+1 < len(data) && strings.IndexByte("\"\\\n", data[j+1]) >= 0:
+				escaped = true
+			default:
+				word.WriteByte(c)
+			}
+		case c == '#' && lineStart:
+			for j+1 < len(data) && data[j+1] != '\n' {
+				j++
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				lines = append(lines, wordLine)
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if j+1 < len(data) && data[j+1] != '\n' {
+				startWord()
+			}
+			escaped = true
+		case c == '\'' || c == '"':
+			startWord()
+			quote = c
+		default:
+			startWord()
+			word.WriteByte(c)
+		}
+		if c == '\n' {
+			line++
+			lineStart = true
+		} else if c != ' ' && c != '\t' {
+			lineStart = false
+		}
+	}
+	if quote != 0 {
+		return nil, nil, fmt.Errorf("%s:%d: %s", name, wordLine, s.message(MsgUnterminatedQuote))
+	}
+	if inWord {
+		args = append(args, word.String())
+		lines = append(lines, wordLine)
+	}
+	return args, lines, nil
+}
+
+// posPrefix formats a position for use at the start of an error message.
+// Only positions in response files are worth mentioning.
+func posPrefix(pos argPos) string {
+	if pos.file == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d: ", pos.file, pos.line)
+}
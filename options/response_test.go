@@ -0,0 +1,138 @@
+package options
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeResponseFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	inner := writeResponseFile(t, dir, "inner", "--ddd\n")
+	outer := writeResponseFile(t, dir, "outer",
+		"# comment\n--ccc 'my val'\nextra1 \"extra 2\"\n@"+inner+"\n")
+	s := NewOptions("TestResponseFiles\n--\nccc= doc [def]\nddd doc").SetResponseFiles(true)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"@" + outer, "@@literal", "--", "@" + inner})
+	if got, want := opt.Get("ccc"), "my val"; got != want {
+		t.Errorf(`opt.Get("ccc")=%q, want=%q`, got, want)
+	}
+	if got, want := opt.GetInt("ddd"), 1; got != want {
+		t.Errorf(`opt.GetInt("ddd")=%d, want=%d`, got, want)
+	}
+	if diff := cmp.Diff([]string{"extra1", "extra 2", "@literal"}, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"@" + inner}, opt.Leftover); diff != "" {
+		t.Errorf("leftover diff (-want+got):\n%s", diff)
+	}
+
+	s.ResponseFiles = false
+	opt = s.Parse([]string{"@" + outer})
+	if diff := cmp.Diff([]string{"@" + outer}, opt.Extra); diff != "" {
+		t.Errorf("extra without ResponseFiles diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestResponseFiles_errorPosition(t *testing.T) {
+	dir := t.TempDir()
+	path := writeResponseFile(t, dir, "args", "--ccc foo\n\n  --unk\n")
+	s := NewOptions("TestResponseFiles_errorPosition\n--\nccc= doc").SetResponseFiles(true)
+	var i int
+	var out bytes.Buffer
+	s.Exit = func(code int) { i = code }
+	s.ErrorWriter = &out
+	s.Parse([]string{"@" + path})
+	if i == 0 {
+		t.Fatalf("expected failure with nonzero code, got=0")
+	}
+	if got, want := out.String(), path+":3: Unkown option: unk\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+}
+
+func TestResponseFiles_recursionLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "self")
+	writeResponseFile(t, dir, "self", "@"+path)
+	s := NewOptions("TestResponseFiles_recursionLimit\n--\nccc= doc").SetResponseFiles(true)
+	var i int
+	var out bytes.Buffer
+	s.Exit = func(code int) { i = code }
+	s.ErrorWriter = &out
+	s.MaxResponseDepth = 3
+	s.Parse([]string{"@" + path})
+	if i == 0 {
+		t.Fatalf("expected failure with nonzero code, got=0")
+	}
+	if got, want := out.String(), path+":1: response files nested too deeply"; !strings.HasPrefix(got, want) {
+		t.Errorf("error output=%q, want prefix %q", got, want)
+	}
+}
+
+func TestReadResponseFile_quoting(t *testing.T) {
+	dir := t.TempDir()
+	path := writeResponseFile(t, dir, "q",
+		`plain "double \"quoted\"" 'single \ quoted' back\ slash`+"\n"+
+			"cont\\\ninued \"\" # not a comment\n")
+	args, lines, err := new(OptionSpec).readResponseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"plain", `double "quoted"`, `single \ quoted`, "back slash",
+		"continued", "", "#", "not", "a", "comment"}
+	if diff := cmp.Diff(want, args); diff != "" {
+		t.Errorf("args diff (-want+got):\n%s", diff)
+	}
+	if got, want := lines[4], 2; got != want {
+		t.Errorf("line of continued line=%d, want=%d", got, want)
+	}
+
+	bad := writeResponseFile(t, dir, "bad", "ok\n'unterminated\n")
+	if _, _, err := new(OptionSpec).readResponseFile(bad); err == nil {
+		t.Errorf("unterminated quote: expected error")
+	}
+}
+
+func TestReadResponseFile_notUTF8(t *testing.T) {
+	// "café.txt" and "naïve" in Latin-1, which must come through unchanged.
+	path := writeResponseFile(t, t.TempDir(), "latin1", "caf\xe9.txt 'na\xefve' \\\xe9\n")
+	args, _, err := new(OptionSpec).readResponseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"caf\xe9.txt", "na\xefve", "\xe9"}, args); diff != "" {
+		t.Errorf("args diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestResponseFiles_lines(t *testing.T) {
+	path := writeResponseFile(t, t.TempDir(), "lines",
+		"--out\r\nC:\\Users\\me\\file.c\n\n/path/with space/x.c\n# not a comment\n")
+	s := NewOptions("TestResponseFiles_lines\n--\nout doc").SetResponseFiles(true).SetResponseFileLines(true)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"@" + path})
+	want := []string{`C:\Users\me\file.c`, "/path/with space/x.c", "# not a comment"}
+	if diff := cmp.Diff(want, opt.Extra); diff != "" {
+		t.Errorf("extra diff (-want+got):\n%s", diff)
+	}
+	if got, want := opt.Source("out").Line, 1; got != want {
+		t.Errorf(`opt.Source("out").Line=%d, want=%d`, got, want)
+	}
+	if _, lines, _ := s.readResponseFile(path); lines[2] != 4 {
+		t.Errorf("line of /path/with space/x.c=%d, want=4", lines[2])
+	}
+}
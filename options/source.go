@@ -0,0 +1,141 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SourceKind tells what kind of place an option value came from.
+type SourceKind int
+
+const (
+	SourceNone    SourceKind = iota // The option has no value
+	SourceDefault                   // The default given in the spec
+	SourceEnv                       // An environment variable
+	SourceFile                      // A configuration file
+	SourceArgs                      // The command line
+	SourcePrompt                    // An answer to a prompt
+)
+
+// Source describes where an option value came from.
+type Source struct {
+	Kind  SourceKind
+	Name  string // Environment variable or file name, or response file for SourceArgs
+	Line  int    // Line in the file named by Name, if known
+	Index int    // Index of the argument given to Parse (SourceArgs)
+	Flag  string // The flag as presented on the command line (SourceArgs)
+}
+
+// String describes the source for humans, e.g. "default" or
+// "--input-encoding (argument 3)".
+func (src Source) String() string {
+	where := src.Name
+	if where != "" && src.Line > 0 {
+		where += fmt.Sprintf(":%d", src.Line)
+	}
+	switch src.Kind {
+	case SourceNone:
+		return "unset"
+	case SourceDefault:
+		return "default"
+	case SourceEnv:
+		return "environment variable " + src.Name
+	case SourceFile:
+		return where
+	case SourcePrompt:
+		return "prompt"
+	case SourceArgs:
+		if src.Flag == "" {
+			return "command line" // E.g. decoded from JSON.
+		}
+		if where != "" {
+			return fmt.Sprintf("%s (%s, argument %d)", src.Flag, where, src.Index)
+		}
+		return fmt.Sprintf("%s (argument %d)", src.Flag, src.Index)
+	}
+	return fmt.Sprintf("SourceKind(%d)", src.Kind)
+}
+
+// Source returns where the value of an option, which must be known to this
+// parse, came from.
+func (o *Options) Source(flag string) Source {
+	if !o.known[flag] {
+		panic(fmt.Sprintf("[Programmer error] Unknown option: %s\ndump: %+v", flag, *o))
+	}
+	if _, ok := o.opts[flag]; !ok {
+		return Source{Kind: SourceNone}
+	}
+	return o.sources[flag]
+}
+
+// Set sets the value of an option, which must be known to this parse, and
+// records where it came from. It is meant for programs that also read
+// option values from the environment or configuration files. The values of
+// options taking several arguments are separated by spaces; for map options
+// value is a single "key=value" entry, which is added to the map.
+func (o *Options) Set(flag, value string, src Source) {
+	o.setFor(o.spec, flag, value, src)
+}
+
+// setFor is Set, with s deciding how the values of maps and lists are kept.
+// Options decoded from JSON have no spec of their own.
+func (o *Options) setFor(s *OptionSpec, flag, value string, src Source) {
+	if !o.known[flag] {
+		panic(fmt.Sprintf("[Programmer error] Unknown option: %s\ndump: %+v", flag, *o))
+	}
+	if o.sources == nil {
+		o.sources = make(map[string]Source)
+	}
+	o.opts[flag] = value
+	o.sources[flag] = src
+	switch {
+	case s == nil:
+	case s.isMap(flag):
+		m := o.maps[flag]
+		if m == nil {
+			m = &Map{}
+			o.setMap(flag, m)
+		}
+		if key, val, ok := mapEntry(value); ok {
+			m.set(key, val)
+		}
+	case s.arity(flag) > 1:
+		o.setList(flag, strings.Fields(value))
+	}
+}
+
+// setMap records the entries of a map option.
+func (o *Options) setMap(flag string, m *Map) {
+	if o.maps == nil {
+		o.maps = make(map[string]*Map)
+	}
+	o.maps[flag] = m
+}
+
+// setList records the values of an option taking several arguments.
+func (o *Options) setList(flag string, values []string) {
+	if o.lists == nil {
+		o.lists = make(map[string][]string)
+	}
+	o.lists[flag] = values
+}
+
+// Explain returns a listing of every option that has a value, one per line,
+// with the value and where it came from. It is meant for debugging.
+func (o *Options) Explain() string {
+	var names []string
+	for name := range o.opts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%q\t%s\n", name, o.opts[name], o.Source(name))
+	}
+	return b.String()
+}
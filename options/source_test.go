@@ -0,0 +1,60 @@
+package options
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	path := writeResponseFile(t, dir, "args", "\n-vv\n")
+	s := NewOptions("TestSource\n--\na,bbb,ccc= doc [def]\nddd= doc\neee= doc\nv,verbose doc").SetResponseFiles(true)
+	s.Exit = exitToPanic
+	opt := s.Parse([]string{"extra", "--bbb", "val", "@" + path})
+
+	tests := []struct {
+		flag string
+		want Source
+	}{
+		{"ccc", Source{Kind: SourceArgs, Index: 1, Flag: "--bbb"}},
+		{"ddd", Source{Kind: SourceNone}},
+		{"verbose", Source{Kind: SourceArgs, Name: path, Line: 2, Index: 3, Flag: "-v"}},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, opt.Source(tt.flag)); diff != "" {
+			t.Errorf("opt.Source(%q) diff (-want+got):\n%s", tt.flag, diff)
+		}
+	}
+
+	opt = s.Parse(nil)
+	if diff := cmp.Diff(Source{Kind: SourceDefault}, opt.Source("ccc")); diff != "" {
+		t.Errorf(`opt.Source("ccc") diff (-want+got):\n%s`, diff)
+	}
+	opt.Set("eee", "env", Source{Kind: SourceEnv, Name: "EEE"})
+	opt.Set("verbose", "2", Source{Kind: SourceFile, Name: filepath.Join("etc", "rc"), Line: 7})
+	want := `ccc="def"	default
+eee="env"	environment variable EEE
+verbose="2"	` + filepath.Join("etc", "rc") + `:7
+`
+	if got := opt.Explain(); got != want {
+		t.Errorf("opt.Explain()=%q, want=%q", got, want)
+	}
+}
+
+func TestSource_String(t *testing.T) {
+	tests := []struct {
+		src  Source
+		want string
+	}{
+		{Source{}, "unset"},
+		{Source{Kind: SourceArgs, Index: 3, Flag: "-i"}, "-i (argument 3)"},
+		{Source{Kind: SourceArgs, Name: "args", Line: 2, Index: 0, Flag: "-i"}, "-i (args:2, argument 0)"},
+	}
+	for _, tt := range tests {
+		if got := tt.src.String(); got != tt.want {
+			t.Errorf("%+v.String()=%q, want=%q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2012 Google Inc. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SpecError describes a problem with a spec.
+type SpecError struct {
+	Line   int    // Line in the spec, starting at 1
+	Column int    // Byte offset in the line, starting at 1
+	Msg    string // Description of the problem
+	Fatal  bool   // Whether NewOptions rejects the spec, or it is merely suspicious
+}
+
+func (e SpecError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ValidateSpec reports all the problems with a spec, in order: those that
+// make NewOptions panic, such as unparseable lines and duplicate or empty
+// names, and those that are likely mistakes, such as bracketed text that
+// will be read as a default value, defaults for options that take no
+// argument and single-letter canonical names.
+func ValidateSpec(spec string) []SpecError {
+	_, errs := parseSpec(spec)
+	return errs
+}
+
+// parseSpec does the work of NewOptions, going on after problems with the
+// spec so that they can all be reported.
+func parseSpec(spec string) (*OptionSpec, []SpecError) {
+	s := &OptionSpec{UnknownOptionsFatal: true, UnknownValuesFatal: false,
+		MaxResponseDepth: DefaultMaxResponseDepth, Exit: os.Exit}
+	s.aliases = make(map[string]string)
+	s.defaults = make(map[string]string)
+	s.requiresArg = make(map[string]bool)
+	s.metavars = make(map[string][]string)
+	s.counters = make(map[string]counter)
+	s.decrements = make(map[string]bool)
+	s.toggles = make(map[string]bool)
+	s.required = make(map[string]bool)
+	s.secrets = make(map[string]bool)
+	firstCol := 1 // Column of the first byte of the first line.
+	if strings.HasPrefix(spec, "+") {
+		s.StopAtFirstExtra = true
+		spec = spec[1:]
+		firstCol++
+	}
+
+	var errs []SpecError
+	line, col := 0, 0
+	report := func(offset int, fatal bool, format string, args ...interface{}) {
+		errs = append(errs, SpecError{Line: line, Column: col + offset, Msg: fmt.Sprintf(format, args...), Fatal: fatal})
+	}
+	addUsage := func(text string) {
+		s.usage = append(s.usage, usageLine{text: text})
+	}
+	stanza := 0 // synopsis
+	specLines := strings.Split(spec, "\n")
+	for n, l := range specLines {
+		line, col = n+1, 1
+		if n == 0 {
+			col = firstCol
+		}
+		switch stanza {
+		case 0:
+			{
+				if l == "--" {
+					addUsage("\n")
+					stanza++
+					continue
+				}
+				addUsage(l + "\n")
+			}
+		case 1:
+			{
+				if l == "" {
+					addUsage("\n")
+					continue
+				}
+				if l == "--" {
+					addUsage("\n")
+					stanza++
+					continue
+				}
+				nameText, flags, help, helpOffset, bad := scanOptionLine(l)
+				if bad >= 0 {
+					report(bad, true, "no parse: %s", l)
+					continue
+				}
+				names := strings.Split(nameText, ",")
+				canonical := names[len(names)-1]
+				offset, ok := 0, true
+				seen := make(map[string]bool)
+				for _, name := range names {
+					if _, dup := s.aliases[name]; dup || seen[name] {
+						report(offset, true, "duplicate name: %s", name)
+						ok = false
+					} else if name == "" || name == "-" || name == "--" {
+						report(offset, true, "bad name: %q", name)
+						ok = false
+					}
+					seen[name] = true
+					offset += len(name) + 1
+				}
+				if !ok {
+					continue
+				}
+				hidden, bounded, numeric, toggle, target := false, false, false, false, ""
+				required, secret := false, false
+				for j := 0; j < len(flags); j++ {
+					switch f := flags[j]; {
+					case f == '=' && !s.requiresArg[canonical]:
+						s.requiresArg[canonical] = true
+						k := j + 1
+						for k < len(flags) && (isMetavarByte(flags[k]) || flags[k] == '=' && k > j+1) {
+							k++
+						}
+						if k == j+1 {
+							break
+						}
+						metavars := strings.Split(flags[j+1:k], ",")
+						for m, at := range metavars {
+							if at == "" {
+								report(len(nameText)+j+1, true, "empty metavariable %d in %s", m+1, flags[j+1:k])
+								ok = false
+							}
+						}
+						if len(metavars) > 1 && strings.Contains(flags[j+1:k], "=") {
+							report(len(nameText)+j+1, true, "map option %s must have a single metavariable: %s", canonical, flags[j+1:k])
+							ok = false
+						}
+						s.metavars[canonical] = metavars
+						j = k - 1
+					case f == '*' && !hidden:
+						hidden = true
+					case f == '#' && !numeric:
+						numeric = true
+					case f == '+' && !toggle:
+						toggle = true
+					case f == '!' && !required:
+						required = true
+					case f == '$' && !secret:
+						secret = true
+					case f == '{' && !bounded:
+						bounded = true
+						k := strings.IndexByte(flags[j:], '}')
+						if k < 0 {
+							report(len(nameText)+j, true, "bad flags: %s", flags)
+							ok = false
+							break
+						}
+						c, err := parseBounds(flags[j+1 : j+k])
+						if err != nil {
+							report(len(nameText)+j+1, true, "bad bounds {%s}: %v", flags[j+1:j+k], err)
+							ok = false
+						}
+						s.counters[canonical] = c
+						j += k
+					case f == '~' && target == "":
+						k := j + 1
+						for k < len(flags) && (isWordByte(flags[k]) || flags[k] == '-') {
+							k++
+						}
+						target = flags[j+1 : k]
+						if s.aliases[target] != target || s.requiresArg[target] || target == "" {
+							report(len(nameText)+j+1, true, "~ must name an option without arguments declared above: %q", target)
+							ok = false
+						}
+						j = k - 1
+					default:
+						report(len(nameText)+j, true, "bad flags: %s", flags)
+						ok = false
+					}
+				}
+				if toggle && s.requiresArg[canonical] {
+					report(len(nameText), true, "toggle option %s cannot take an argument", canonical)
+					ok = false
+				}
+				if (bounded || target != "") && s.requiresArg[canonical] {
+					report(len(nameText), true, "counter option %s cannot take an argument", canonical)
+					ok = false
+				}
+				if numeric && (s.arity(canonical) != 1 || s.isMap(canonical)) {
+					report(len(nameText), true, "option %s must take a single argument to be given as -NUM", canonical)
+					ok = false
+				} else if numeric && s.numeric != "" {
+					report(len(nameText), true, "both %s and %s are given as -NUM", s.numeric, canonical)
+					ok = false
+				}
+				if required && !s.requiresArg[canonical] {
+					report(len(nameText), true, "required option %s must take an argument", canonical)
+					ok = false
+				}
+				if secret && !s.requiresArg[canonical] {
+					report(len(nameText), true, "secret option %s must take an argument", canonical)
+					ok = false
+				}
+				if bounded && target != "" {
+					report(len(nameText), true, "option %s counts down %s and cannot have bounds of its own", canonical, target)
+					ok = false
+				}
+				if !ok {
+					delete(s.requiresArg, canonical)
+					delete(s.counters, canonical)
+					continue
+				}
+				if numeric {
+					s.numeric = canonical
+				}
+				if toggle {
+					s.toggles[canonical] = true
+				}
+				if required {
+					s.required[canonical] = true
+				}
+				if secret {
+					s.secrets[canonical] = true
+				}
+				if target != "" {
+					for _, name := range names {
+						s.decrements[name] = true
+					}
+					canonical = target
+					if _, at, found := defaultValue(help); found {
+						report(helpOffset+at, false, "default value for option counting down %s is ignored", target)
+					}
+					s.counters[target] = s.counters[target] // Even without bounds.
+				}
+				for _, name := range names {
+					s.aliases[name] = canonical
+				}
+				if def, at, found := defaultValue(help); found && target == "" {
+					s.defaults[canonical] = def
+					if strings.ContainsAny(def, "[]") {
+						report(helpOffset+at, false, "help text will be read as default value %q", def)
+					}
+					if c, counter := s.counters[canonical]; counter {
+						if n, err := strconv.Atoi(def); err != nil {
+							report(helpOffset+at, true, "default value %q for counter option %s is not a number", def, canonical)
+						} else if c.clamp(n) != n {
+							report(helpOffset+at, true, "default value %d for counter option %s is outside its bounds %v", n, canonical, c)
+						}
+					} else if !s.requiresArg[canonical] {
+						report(helpOffset+at, false, "default value %q for option %s, which takes no argument", def, canonical)
+					} else if s.isMap(canonical) {
+						for _, entry := range strings.Fields(def) {
+							if _, _, ok := mapEntry(entry); !ok {
+								report(helpOffset+at, false, "default value %q for map option %s has an entry without \"=\": %s", def, canonical, entry)
+							}
+						}
+					} else if n := s.arity(canonical); n > 1 && len(strings.Fields(def)) != n {
+						report(helpOffset+at, false, "default value %q for option %s has %d values, want %d", def, canonical, len(strings.Fields(def)), n)
+					}
+				} else if trimmed := strings.TrimRight(help, " \t"); trimmed != help {
+					if _, at, found := defaultValue(trimmed); found {
+						report(helpOffset+at, false, "trailing space after bracketed text; it is not a default value")
+					}
+				}
+				if len(canonical) == 1 && target == "" {
+					report(len(nameText)-1, false, "canonical name %s is a single letter; consider adding a long name last", canonical)
+				}
+				s.usage = append(s.usage, usageLine{text: help, names: names, hidden: hidden})
+			}
+		case 2:
+			{
+				if l == "" {
+					addUsage("\n")
+					continue
+				}
+				if l == "--" {
+					report(0, true, "no parse: %s (more than two \"--\" lines)", l)
+					continue
+				}
+				parts := positionalSpec.FindStringSubmatch(l)
+				if parts == nil {
+					report(0, true, "no parse: %s", l)
+					continue
+				}
+				p := positional{name: parts[1], kind: parts[2]}
+				ok := true
+				for _, prev := range s.positionals {
+					switch {
+					case prev.name == p.name:
+						report(0, true, "duplicate name: %s", p.name)
+					case prev.variadic() && p.kind != "":
+						report(0, true, "optional argument after variadic argument: %s", p.name)
+					case p.kind == "" && prev.kind == "?":
+						report(0, true, "required argument after optional argument: %s", p.name)
+					default:
+						continue
+					}
+					ok = false
+					break
+				}
+				if !ok {
+					continue
+				}
+				s.positionals = append(s.positionals, p)
+				line := "  " + p.String()
+				if parts[3] != "" {
+					line += "  " + parts[3]
+				}
+				addUsage(line + "\n")
+			}
+		}
+	}
+	s.indexNames()
+	s.renderUsage()
+	return s, errs
+}
+
+// scanOptionLine splits a line in the options section of a spec into the
+// names, the flags following them, and the help text, whose offset it also
+// returns. If the line is malformed, bad is the offset of the problem;
+// otherwise it is -1.
+func scanOptionLine(l string) (names, flags, help string, helpOffset, bad int) {
+	i := 0
+	for i < len(l) && (isWordByte(l[i]) || l[i] == '-' || l[i] == ',') {
+		i++
+	}
+	if i == 0 {
+		return "", "", "", 0, 0
+	}
+	j := i
+	for j < len(l) && !isSpaceByte(l[j]) {
+		j++
+	}
+	k := j
+	for k < len(l) && isSpaceByte(l[k]) {
+		k++
+	}
+	if k == j {
+		return "", "", "", 0, j
+	}
+	return l[:i], l[i:j], l[k:], k, -1
+}
+
+// defaultValue finds the default value at the end of some help text, as in
+// "charset input is encoded in [utf-8]". It starts at the first "[".
+func defaultValue(help string) (def string, offset int, found bool) {
+	if !strings.HasSuffix(help, "]") {
+		return "", 0, false
+	}
+	offset = strings.IndexByte(help, '[')
+	if offset < 0 || offset == len(help)-1 {
+		return "", 0, false
+	}
+	return help[offset+1 : len(help)-1], offset, true
+}
+
+// parseBounds parses the bounds of a counter option, as in "0,3", where
+// either number may be left out.
+func parseBounds(text string) (c counter, err error) {
+	lo, hi, found := strings.Cut(text, ",")
+	if !found {
+		return c, fmt.Errorf("want {min,max}")
+	}
+	if lo != "" {
+		if c.min, err = strconv.Atoi(lo); err != nil {
+			return c, fmt.Errorf("bad minimum %q", lo)
+		}
+		c.hasMin = true
+	}
+	if hi != "" {
+		if c.max, err = strconv.Atoi(hi); err != nil {
+			return c, fmt.Errorf("bad maximum %q", hi)
+		}
+		c.hasMax = true
+	}
+	if c.hasMin && c.hasMax && c.min > c.max {
+		return c, fmt.Errorf("minimum above maximum")
+	}
+	return c, nil
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isMetavarByte(c byte) bool {
+	return isWordByte(c) || c == '-' || c == ','
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,60 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateSpec(t *testing.T) {
+	spec := `synopsis
+--
+a,bbb= doc a [def]
+=bad doc
+c,bbb,,ddd doc
+eee== doc
+fff doc [on]
+ggg= see [1] for more [x]
+hhh= not a default [x]  
+i doc
+--
+src
+src
+--
+`
+	want := []SpecError{
+		{Line: 4, Column: 1, Msg: "no parse: =bad doc", Fatal: true},
+		{Line: 5, Column: 3, Msg: "duplicate name: bbb", Fatal: true},
+		{Line: 5, Column: 7, Msg: `bad name: ""`, Fatal: true},
+		{Line: 6, Column: 5, Msg: "bad flags: ==", Fatal: true},
+		{Line: 7, Column: 9, Msg: `default value "on" for option fff, which takes no argument`},
+		{Line: 8, Column: 10, Msg: `help text will be read as default value "1] for more [x"`},
+		{Line: 9, Column: 20, Msg: "trailing space after bracketed text; it is not a default value"},
+		{Line: 10, Column: 1, Msg: "canonical name i is a single letter; consider adding a long name last"},
+		{Line: 13, Column: 1, Msg: "duplicate name: src", Fatal: true},
+		{Line: 14, Column: 1, Msg: `no parse: -- (more than two "--" lines)`, Fatal: true},
+	}
+	if diff := cmp.Diff(want, ValidateSpec(spec)); diff != "" {
+		t.Errorf("ValidateSpec diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestValidateSpec_metavars(t *testing.T) {
+	spec := "synopsis\n--\npoint=X,Y doc [1]\nfile=FILE doc\nrename=FROM,,TO doc\nsize=W,H* doc [1 2]"
+	want := []SpecError{
+		{Line: 3, Column: 15, Msg: `default value "1" for option point has 1 values, want 2`},
+		{Line: 5, Column: 8, Msg: "empty metavariable 2 in FROM,,TO", Fatal: true},
+	}
+	if diff := cmp.Diff(want, ValidateSpec(spec)); diff != "" {
+		t.Errorf("ValidateSpec diff (-want+got):\n%s", diff)
+	}
+}
+
+func TestNewOptions_badSpec(t *testing.T) {
+	defer func() {
+		if got, want := recover(), "4:3: duplicate name: bbb"; got != want {
+			t.Errorf("NewOptions panicked with %q, want %q", got, want)
+		}
+	}()
+	NewOptions("synopsis\n--\na,bbb doc\nc,bbb doc")
+}